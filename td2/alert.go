@@ -396,16 +396,29 @@ func (c *Config) alert(chainName, message, severity string, resolved bool, id *s
 // and also updates a few prometheus stats
 // FIXME: not watching for nodes that are lagging the head block!
 func (cc *ChainConfig) watch() {
-	var missedAlarm, pctAlarm, noNodes bool
+	var missedAlarm, pctAlarm, noNodes, oracleMissedAlarm, oraclePctAlarm bool
+	var proposalAlarm, proposalSeen int64 // the height of the open missed proposal alarm, and the last one alerted on
+	var delegationAlarm, blockTimeAlarm, versionAlarm, stalledAlarm string
 	inactive := "jailed"
 	nodeAlarms := make(map[string]bool)
+	balanceAlarms := make(map[string]bool)
+	keyAlarms := make(map[string]string)
+	consistencyAlarms := make(map[string]string)
+	var stallSummary chan *consensusSummary // the stall diagnostics, while they are running
+
+	// additional validators on a chain share nodes with the primary, only the primary alerts on node health.
+	isPrimary := cc.primary == nil
+	nodes := cc.Nodes
+	if !isPrimary {
+		nodes = nil
+	}
 
 	// wait until we have a moniker:
 	noNodesSec := 0 // delay a no-nodes alarm for 30 seconds, too noisy.
 	for {
 		if cc.valInfo == nil || cc.valInfo.Moniker == "not connected" {
 			time.Sleep(time.Second)
-			if cc.Alerts.AlertIfNoServers && !noNodes && cc.noNodes && noNodesSec >= 60*td.NodeDownMin {
+			if isPrimary && cc.Alerts.AlertIfNoServers && !noNodes && cc.noNodes && noNodesSec >= 60*td.NodeDownMin {
 				noNodes = true
 				td.alert(
 					cc.name,
@@ -423,7 +436,7 @@ func (cc *ChainConfig) watch() {
 	}
 	// initial stat creation for nodes, we only update again if the node is positive
 	if td.Prom {
-		for _, node := range cc.Nodes {
+		for _, node := range nodes {
 			td.statsChan <- cc.mkUpdate(metricNodeDownSeconds, 0, node.Url)
 		}
 	}
@@ -433,7 +446,7 @@ func (cc *ChainConfig) watch() {
 
 		// alert if we can't monitor
 		switch {
-		case cc.Alerts.AlertIfNoServers && !noNodes && cc.noNodes:
+		case isPrimary && cc.Alerts.AlertIfNoServers && !noNodes && cc.noNodes:
 			noNodesSec += 2
 			if noNodesSec <= 30*td.NodeDownMin {
 				if noNodesSec%20 == 0 {
@@ -451,7 +464,7 @@ func (cc *ChainConfig) watch() {
 					&cc.valInfo.Valcons,
 				)
 			}
-		case cc.Alerts.AlertIfNoServers && noNodes && !cc.noNodes:
+		case isPrimary && cc.Alerts.AlertIfNoServers && noNodes && !cc.noNodes:
 			noNodes = false
 			td.alert(
 				cc.name,
@@ -468,25 +481,87 @@ func (cc *ChainConfig) watch() {
 		if cc.Alerts.StalledAlerts && !cc.lastBlockAlarm && !cc.lastBlockTime.IsZero() &&
 			cc.lastBlockTime.Before(time.Now().Add(time.Duration(-cc.Alerts.Stalled)*time.Minute)) {
 
-			// chain is stalled send an alert!
+			// chain is stalled send an alert! Include what the nodes think is happening with consensus, asking them
+			// can take a while so the alert is sent once they respond.
 			cc.lastBlockAlarm = true
+			stalledAlarm = fmt.Sprintf("stalled: have not seen a new block on %s in %d minutes", cc.ChainId, cc.Alerts.Stalled)
+			stallSummary = make(chan *consensusSummary, 1)
+			go func(result chan *consensusSummary) {
+				ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+				defer cancel()
+				summary, err := cc.stallDiagnostics(ctx)
+				if err != nil {
+					l("❓ getting consensus state for stalled chain", cc.ChainId, err)
+				}
+				result <- summary
+			}(stallSummary)
+		} else if cc.Alerts.StalledAlerts && cc.lastBlockAlarm && cc.lastBlockTime.IsZero() {
+			cc.lastBlockAlarm = false
+			if stalledAlarm == "" {
+				stalledAlarm = fmt.Sprintf("stalled: have not seen a new block on %s in %d minutes", cc.ChainId, cc.Alerts.Stalled)
+			}
+			// nothing was sent yet if the diagnostics are still running
+			if stallSummary == nil {
+				td.alert(
+					cc.name,
+					stalledAlarm,
+					"info",
+					true,
+					&cc.valInfo.Valcons,
+				)
+			}
+			stallSummary = nil
+			alarms.clearNoBlocks(cc.name)
+		}
+		select {
+		case summary := <-stallSummary:
+			stallSummary = nil
+			// blocks may have been seen again while the nodes were being asked
+			if !cc.lastBlockAlarm {
+				break
+			}
+			if summary != nil {
+				stalledAlarm += ": " + summary.String()
+				l(fmt.Sprintf("🔎 %-12s %s", cc.ChainId, summary))
+			}
 			td.alert(
 				cc.name,
-				fmt.Sprintf("stalled: have not seen a new block on %s in %d minutes", cc.ChainId, cc.Alerts.Stalled),
+				stalledAlarm,
 				"critical",
 				false,
 				&cc.valInfo.Valcons,
 			)
-		} else if cc.Alerts.StalledAlerts && cc.lastBlockAlarm && cc.lastBlockTime.IsZero() {
-			cc.lastBlockAlarm = false
+			if summary != nil {
+				cc.updateStalled(stalledAlarm)
+			}
+		default:
+		}
+
+		// block time degradation, the average is only reported after a full window of intervals has been seen. The
+		// baseline may be learned, so keep the message that was sent to be able to clear it.
+		if cc.Alerts.BlockTimeAlerts && blockTimeAlarm == "" && cc.blockTimes.degraded(cc.Alerts.BlockTimeMultiple) {
+			blockTimeAlarm = fmt.Sprintf("average block time on %s is more than %.1fx the %.2fs baseline",
+				cc.ChainId, cc.Alerts.BlockTimeMultiple, cc.blockTimes.baseline)
+			id := cc.valInfo.Valcons + "blocktime"
+			td.alert(
+				cc.name,
+				blockTimeAlarm,
+				cc.Alerts.BlockTimePriority,
+				false,
+				&id,
+			)
+			cc.activeAlerts = alarms.getCount(cc.name)
+		} else if blockTimeAlarm != "" && !cc.blockTimes.degraded(cc.Alerts.BlockTimeMultiple) {
+			id := cc.valInfo.Valcons + "blocktime"
 			td.alert(
 				cc.name,
-				fmt.Sprintf("stalled: have not seen a new block on %s in %d minutes", cc.ChainId, cc.Alerts.Stalled),
+				blockTimeAlarm,
 				"info",
 				true,
-				&cc.valInfo.Valcons,
+				&id,
 			)
-			alarms.clearNoBlocks(cc.name)
+			blockTimeAlarm = ""
+			cc.activeAlerts = alarms.getCount(cc.name)
 		}
 
 		// jailed detection - only alert if it changes.
@@ -545,6 +620,31 @@ func (cc *ChainConfig) watch() {
 			cc.activeAlerts = alarms.getCount(cc.name)
 		}
 
+		// missed proposal alarms, cleared once a later block has been seen
+		if cc.Alerts.ProposalAlerts && proposalAlarm == 0 && cc.proposalMissed > proposalSeen {
+			proposalAlarm, proposalSeen = cc.proposalMissed, cc.proposalMissed
+			id := cc.valInfo.Valcons + "proposal"
+			td.alert(
+				cc.name,
+				fmt.Sprintf("%s missed its turn to propose block %d on %s", cc.valInfo.Moniker, proposalAlarm, cc.ChainId),
+				cc.Alerts.ProposalPriority,
+				false,
+				&id,
+			)
+			cc.activeAlerts = alarms.getCount(cc.name)
+		} else if proposalAlarm != 0 && cc.lastBlockNum > proposalAlarm {
+			id := cc.valInfo.Valcons + "proposal"
+			td.alert(
+				cc.name,
+				fmt.Sprintf("%s missed its turn to propose block %d on %s", cc.valInfo.Moniker, proposalAlarm, cc.ChainId),
+				"info",
+				true,
+				&id,
+			)
+			proposalAlarm = 0
+			cc.activeAlerts = alarms.getCount(cc.name)
+		}
+
 		// window percentage missed block alarms
 		if cc.Alerts.PercentageAlerts && !pctAlarm && 100*float64(cc.valInfo.Missed)/float64(cc.valInfo.Window) > float64(cc.Alerts.Window) {
 			// alert on missed block counter!
@@ -572,8 +672,192 @@ func (cc *ChainConfig) watch() {
 			cc.activeAlerts = alarms.getCount(cc.name)
 		}
 
+		// large delegation changes, the message is built when the delegations are refreshed
+		if cc.delegationAlert != delegationAlarm {
+			id := cc.valInfo.Valcons + "delegations"
+			if delegationAlarm != "" {
+				td.alert(
+					cc.name,
+					delegationAlarm,
+					"info",
+					true,
+					&id,
+				)
+			}
+			delegationAlarm = cc.delegationAlert
+			if delegationAlarm != "" {
+				td.alert(
+					cc.name,
+					delegationAlarm,
+					cc.Alerts.DelegationPriority,
+					false,
+					&id,
+				)
+			}
+			cc.activeAlerts = alarms.getCount(cc.name)
+		}
+
+		// oracle vote alarms, same as the missed block alarms but for price-feeder votes
+		if cc.Oracle.Enabled && cc.oracle != nil {
+			if !oracleMissedAlarm && cc.Oracle.ConsecutiveAlerts && cc.oracle.consecutive >= cc.Oracle.ConsecutiveMissed {
+				oracleMissedAlarm = true
+				id := cc.valInfo.Valcons + "oracle_consecutive"
+				td.alert(
+					cc.name,
+					fmt.Sprintf("%s has missed %d oracle votes on %s", cc.valInfo.Moniker, cc.Oracle.ConsecutiveMissed, cc.ChainId),
+					cc.Oracle.ConsecutivePriority,
+					false,
+					&id,
+				)
+				cc.activeAlerts = alarms.getCount(cc.name)
+			} else if oracleMissedAlarm && cc.oracle.consecutive < cc.Oracle.ConsecutiveMissed {
+				oracleMissedAlarm = false
+				id := cc.valInfo.Valcons + "oracle_consecutive"
+				td.alert(
+					cc.name,
+					fmt.Sprintf("%s has missed %d oracle votes on %s", cc.valInfo.Moniker, cc.Oracle.ConsecutiveMissed, cc.ChainId),
+					"info",
+					true,
+					&id,
+				)
+				cc.activeAlerts = alarms.getCount(cc.name)
+			}
+
+			if !oraclePctAlarm && cc.Oracle.PercentageAlerts && cc.oracle.pct() > float64(cc.Oracle.Window) {
+				oraclePctAlarm = true
+				id := cc.valInfo.Valcons + "oracle_percent"
+				td.alert(
+					cc.name,
+					fmt.Sprintf("%s has missed > %d%% of the oracle slashing window's votes on %s", cc.valInfo.Moniker, cc.Oracle.Window, cc.ChainId),
+					cc.Oracle.PercentagePriority,
+					false,
+					&id,
+				)
+				cc.activeAlerts = alarms.getCount(cc.name)
+			} else if oraclePctAlarm && cc.oracle.pct() < float64(cc.Oracle.Window) {
+				oraclePctAlarm = false
+				id := cc.valInfo.Valcons + "oracle_percent"
+				td.alert(
+					cc.name,
+					fmt.Sprintf("%s has missed > %d%% of the oracle slashing window's votes on %s", cc.valInfo.Moniker, cc.Oracle.Window, cc.ChainId),
+					"info",
+					true,
+					&id,
+				)
+				cc.activeAlerts = alarms.getCount(cc.name)
+			}
+		}
+
+		// version skew, the message lists the versions running so clear the previous alarm if it changes
+		if skew := cc.versionSkew(); isPrimary && cc.Alerts.VersionAlerts && skew != versionAlarm {
+			id := cc.valInfo.Valcons + "version"
+			if versionAlarm != "" {
+				td.alert(
+					cc.name,
+					versionAlarm,
+					"info",
+					true,
+					&id,
+				)
+			}
+			versionAlarm = skew
+			if versionAlarm != "" {
+				td.alert(
+					cc.name,
+					versionAlarm,
+					cc.Alerts.VersionPriority,
+					false,
+					&id,
+				)
+			}
+			cc.activeAlerts = alarms.getCount(cc.name)
+		}
+
+		// low balance alarms, accounts that could not be queried are not in the map and keep their current state
+		for msg, low := range cc.lowBalances {
+			id := cc.valInfo.Valcons + "balance" + msg
+			if low && !balanceAlarms[msg] {
+				balanceAlarms[msg] = true
+				td.alert(
+					cc.name,
+					msg,
+					cc.Alerts.BalancePriority,
+					false,
+					&id,
+				)
+				cc.activeAlerts = alarms.getCount(cc.name)
+			} else if !low && balanceAlarms[msg] {
+				balanceAlarms[msg] = false
+				td.alert(
+					cc.name,
+					msg,
+					"info",
+					true,
+					&id,
+				)
+				cc.activeAlerts = alarms.getCount(cc.name)
+			}
+		}
+
+		// consensus key alarms for validator nodes
+		for _, node := range nodes {
+			if !node.ValidatorNode || node.keyProblem == keyAlarms[node.Url] {
+				continue
+			}
+			id := node.Url + "consensus_key"
+			if keyAlarms[node.Url] != "" {
+				td.alert(
+					cc.name,
+					keyAlarms[node.Url],
+					"info",
+					true,
+					&id,
+				)
+			}
+			keyAlarms[node.Url] = node.keyProblem
+			if node.keyProblem != "" {
+				l("🔑", node.keyProblem)
+				td.alert(
+					cc.name,
+					node.keyProblem,
+					cc.Alerts.ConsensusKeyPriority,
+					false,
+					&id,
+				)
+			}
+			cc.activeAlerts = alarms.getCount(cc.name)
+		}
+
+		// nodes that disagree with the majority of nodes
+		for _, node := range nodes {
+			if !cc.Alerts.ConsistencyAlerts || node.inconsistent == consistencyAlarms[node.Url] {
+				continue
+			}
+			id := node.Url + "consistency"
+			if consistencyAlarms[node.Url] != "" {
+				td.alert(
+					cc.name,
+					consistencyAlarms[node.Url],
+					"info",
+					true,
+					&id,
+				)
+			}
+			consistencyAlarms[node.Url] = node.inconsistent
+			if node.inconsistent != "" {
+				td.alert(
+					cc.name,
+					node.inconsistent,
+					cc.Alerts.ConsistencyPriority,
+					false,
+					&id,
+				)
+			}
+			cc.activeAlerts = alarms.getCount(cc.name)
+		}
+
 		// node down alarms
-		for _, node := range cc.Nodes {
+		for _, node := range nodes {
 			// window percentage missed block alarms
 			if node.AlertIfDown && node.down && !node.wasDown && !node.downSince.IsZero() &&
 				time.Since(node.downSince) > time.Duration(td.NodeDownMin)*time.Minute {
@@ -610,7 +894,7 @@ func (cc *ChainConfig) watch() {
 			// raw block timer, ignoring finalized state
 			td.statsChan <- cc.mkUpdate(metricLastBlockSecondsNotFinal, time.Since(cc.lastBlockTime).Seconds(), "")
 			// update node-down times for prometheus
-			for _, node := range cc.Nodes {
+			for _, node := range nodes {
 				if node.down && !node.downSince.IsZero() {
 					td.statsChan <- cc.mkUpdate(metricNodeDownSeconds, time.Since(node.downSince).Seconds(), node.Url)
 				}
@@ -0,0 +1,234 @@
+package tenderduty
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cosmos/cosmos-sdk/types/bech32"
+	slashing "github.com/cosmos/cosmos-sdk/x/slashing/types"
+)
+
+// Chain backends, selected with the backend setting for a chain.
+const (
+	backendCosmos    = "cosmos-sdk"
+	backendConsensus = "consensus-only"
+)
+
+// validatorInfo is the validator metadata returned by a chainBackend.
+type validatorInfo struct {
+	conspub []byte // the consensus address
+	valcons string
+	moniker string
+	jailed  bool
+	bonded  bool
+}
+
+// signingInfo is the state of the validator's signing window returned by a chainBackend.
+type signingInfo struct {
+	missed     int64
+	window     int64
+	tombstoned bool
+}
+
+// chainBackend looks up a validator and its signing window. Blocks are always monitored using the tendermint RPC,
+// the backend is only responsible for the information that is specific to the chain's application.
+type chainBackend interface {
+	validator(ctx context.Context, cc *ChainConfig) (*validatorInfo, error)
+	signing(ctx context.Context, cc *ChainConfig) (*signingInfo, error)
+}
+
+// backend returns the chainBackend for a chain, consumer chains use the provider for validator information.
+func (cc *ChainConfig) backend() chainBackend {
+	switch {
+	case cc.Consumer.Enabled:
+		return consumerBackend{}
+	case cc.Backend == backendConsensus:
+		return consensusBackend{}
+	}
+	return cosmosBackend{}
+}
+
+// cosmosBackend uses the cosmos-sdk staking and slashing modules.
+type cosmosBackend struct{}
+
+func (cosmosBackend) validator(ctx context.Context, cc *ChainConfig) (*validatorInfo, error) {
+	// Fetch info from /cosmos.staking.v1beta1.Query/Validator
+	// it's easier to ask people to provide valoper since it's readily available on
+	// explorers, so make it easy and lookup the consensus key for them.
+	conspub, moniker, jailed, bonded, err := getVal(ctx, cc.query, cc.ValAddress)
+	if err != nil {
+		return nil, err
+	}
+	info := &validatorInfo{conspub: conspub, moniker: moniker, jailed: jailed, bonded: bonded}
+	if strings.Contains(cc.ValAddress, "valcons") {
+		// no need to change prefix for signing info query
+		info.valcons = cc.ValAddress
+		return info, nil
+	}
+	// need to know the prefix for when we serialize the slashing info query, this is too fragile.
+	// for now, we perform specific chain overrides based on known values because the valoper is used
+	// in so many places.
+	prefix, err := valconsPrefix(cc.ValAddress)
+	if err != nil {
+		return nil, err
+	}
+	info.valcons, err = bech32.ConvertAndEncode(prefix, conspub[:20])
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func (cosmosBackend) signing(ctx context.Context, cc *ChainConfig) (*signingInfo, error) {
+	// get current signing information (tombstoned, missed block count)
+	qSigning := slashing.QuerySigningInfoRequest{ConsAddress: cc.valInfo.Valcons}
+	b, err := qSigning.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := cc.query(ctx, "/cosmos.slashing.v1beta1.Query/SigningInfo", b)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil {
+		return nil, errors.New("could not query validator slashing status, got empty response")
+	}
+	info := &signingInfo{window: cc.valInfo.Window}
+	info.missed, info.tombstoned, err = decodeSigningInfo(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	// finally get the signed blocks window, it only needs to be fetched once
+	if info.window != 0 {
+		return info, nil
+	}
+	qParams := &slashing.QueryParamsRequest{}
+	b, err = qParams.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	resp, err = cc.query(ctx, "/cosmos.slashing.v1beta1.Query/Params", b)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil {
+		return nil, errors.New("🛑 could not query slashing params, got empty response")
+	}
+	info.window, err = decodeSlashingParams(resp)
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// consumerBackend finds the validator on an Interchain Security provider chain, and uses the consumer's slashing
+// module for the signing window if possible.
+type consumerBackend struct{}
+
+func (consumerBackend) validator(ctx context.Context, cc *ChainConfig) (*validatorInfo, error) {
+	conspub, valcons, moniker, jailed, bonded, err := cc.getConsumerVal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &validatorInfo{conspub: conspub, valcons: valcons, moniker: moniker, jailed: jailed, bonded: bonded}, nil
+}
+
+func (consumerBackend) signing(ctx context.Context, cc *ChainConfig) (*signingInfo, error) {
+	if cc.valInfo.Valcons == "" {
+		// without the consumer's prefix the slashing module can't be queried.
+		return cc.observedSigning(), nil
+	}
+	info, err := cosmosBackend{}.signing(ctx, cc)
+	if err != nil {
+		// fall back to observing blocks, the consumer may not have a slashing module.
+		return cc.observedSigning(), nil
+	}
+	return info, nil
+}
+
+// consensusBackend only relies on the tendermint RPC, for chains without the cosmos-sdk staking module. The validator
+// is identified by its consensus address, bonded status is read from /validators, and the signing window is
+// estimated from the blocks that have been seen.
+type consensusBackend struct{}
+
+// consensusAddress decodes a consensus address in either hex or bech32 format.
+func consensusAddress(address string) ([]byte, error) {
+	if strings.Contains(address, "valcons") {
+		_, bz, err := bech32.DecodeAndConvert(address)
+		if err != nil {
+			return nil, errors.New("could not decode and convert your address " + address)
+		}
+		return bz, nil
+	}
+	bz, err := hex.DecodeString(strings.TrimPrefix(strings.ToLower(address), "0x"))
+	if err != nil || len(bz) != 20 {
+		return nil, errors.New("expected a hex or bech32 consensus address, got " + address)
+	}
+	return bz, nil
+}
+
+func (consensusBackend) validator(ctx context.Context, cc *ChainConfig) (*validatorInfo, error) {
+	address := cc.ValAddress
+	if cc.ValconsOverride != "" {
+		address = cc.ValconsOverride
+	}
+	conspub, err := consensusAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	info := &validatorInfo{conspub: conspub, moniker: address}
+	if strings.Contains(address, "valcons") {
+		info.valcons = address
+	}
+	page, perPage := 1, 100
+	for {
+		vals, err := cc.client.Validators(ctx, nil, &page, &perPage)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range vals.Validators {
+			if strings.EqualFold(v.Address.String(), hex.EncodeToString(conspub)) {
+				info.bonded = v.VotingPower > 0
+				return info, nil
+			}
+		}
+		if page*perPage >= vals.Total || len(vals.Validators) == 0 {
+			return info, nil
+		}
+		page += 1
+	}
+}
+
+func (consensusBackend) signing(ctx context.Context, cc *ChainConfig) (*signingInfo, error) {
+	return cc.observedSigning(), nil
+}
+
+// observedSigning estimates the signing window from the blocks that have been seen, for when there is no slashing
+// module to query. The window is the number of blocks shown on the dashboard, so a single miss after startup
+// doesn't look like a large percentage. It runs alongside the handler that records new blocks.
+func (cc *ChainConfig) observedSigning() *signingInfo {
+	cc.resultsMux.RLock()
+	results := append([]int{}, cc.blocksResults...)
+	cc.resultsMux.RUnlock()
+	info := &signingInfo{window: int64(len(results))}
+	for _, status := range results {
+		// -1 is a block that hasn't been seen, live or loaded at startup
+		if status >= 0 && status < int(StatusSigned) {
+			info.missed += 1
+		}
+	}
+	return info
+}
+
+// validBackend checks the backend setting, an empty string is the cosmos-sdk backend.
+func validBackend(backend string) error {
+	switch backend {
+	case "", backendCosmos, backendConsensus:
+		return nil
+	}
+	return fmt.Errorf("unknown backend %q, expected %s or %s", backend, backendCosmos, backendConsensus)
+}
@@ -0,0 +1,120 @@
+package tenderduty
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cosmos/cosmos-sdk/types/bech32"
+	tmjson "github.com/tendermint/tendermint/libs/json"
+	rpchttp "github.com/tendermint/tendermint/rpc/client/http"
+	coretypes "github.com/tendermint/tendermint/rpc/core/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+// validatorsServer is an RPC server that only answers /validators, returning every validator on a single page.
+func validatorsServer(t *testing.T, vals []*types.Validator) *httptest.Server {
+	t.Helper()
+	result, err := tmjson.Marshal(&coretypes.ResultValidators{BlockHeight: 100, Validators: vals, Count: len(vals), Total: len(vals)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := struct {
+			ID json.RawMessage `json:"id"`
+		}{}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		_, _ = fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":%s}`, req.ID, result)
+	}))
+}
+
+func TestConsensusBackend(t *testing.T) {
+	set, _ := newValSet(t, "backend", 3)
+	bonded, unbonding := set.Validators[0].Copy(), set.Validators[1].Copy()
+	unbonding.VotingPower = 0
+	server := validatorsServer(t, []*types.Validator{bonded, unbonding})
+	defer server.Close()
+	client, err := rpchttp.New(server.URL, "/websocket")
+	if err != nil {
+		t.Fatal(err)
+	}
+	valcons, err := bech32.ConvertAndEncode("cosmosvalcons", bonded.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		name     string
+		address  string
+		override string
+		conspub  []byte
+		valcons  string
+		bonded   bool
+	}{
+		{name: "hex", address: "0x" + bonded.Address.String(), conspub: bonded.Address, bonded: true},
+		{name: "lowercase hex", address: hex.EncodeToString(bonded.Address), conspub: bonded.Address, bonded: true},
+		{name: "bech32", address: valcons, conspub: bonded.Address, valcons: valcons, bonded: true},
+		{name: "override", address: "unused", override: valcons, conspub: bonded.Address, valcons: valcons, bonded: true},
+		{name: "zero power", address: unbonding.Address.String(), conspub: unbonding.Address},
+		{name: "not in the set", address: set.Validators[2].Address.String(), conspub: set.Validators[2].Address},
+	} {
+		cc := &ChainConfig{ChainId: "test-1", ValAddress: test.address, ValconsOverride: test.override, client: client}
+		info, err := consensusBackend{}.validator(context.Background(), cc)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if hex.EncodeToString(info.conspub) != hex.EncodeToString(test.conspub) || info.valcons != test.valcons || info.bonded != test.bonded {
+			t.Errorf("%s: unexpected validator %x %q bonded %t", test.name, info.conspub, info.valcons, info.bonded)
+		}
+	}
+
+	for _, address := range []string{"", "cosmosvaloper1invalid", "0xabcd", "not hex"} {
+		cc := &ChainConfig{ChainId: "test-1", ValAddress: address, client: client}
+		if _, err = (consensusBackend{}).validator(context.Background(), cc); err == nil {
+			t.Errorf("expected an error for %q", address)
+		}
+	}
+}
+
+func TestObservedSigning(t *testing.T) {
+	cc := &ChainConfig{blocksResults: make([]int, 8), historical: make([]bool, 8)}
+	for i := range cc.blocksResults {
+		cc.blocksResults[i] = -1
+	}
+	if info := cc.observedSigning(); info.missed != 0 || info.window != 8 {
+		t.Errorf("expected no misses before any blocks, got %d of %d", info.missed, info.window)
+	}
+
+	// the oldest blocks were loaded at startup and aren't in the counters
+	copy(cc.blocksResults, []int{int(StatusSigned), int(StatusPrevote), int(StatusProposed), int(Statusmissed), int(StatusPrecommit)})
+	copy(cc.historical[3:], []bool{true, true})
+	cc.statTotalSigns, cc.statTotalMiss = 2, 1
+	if info := cc.observedSigning(); info.missed != 3 || info.window != 8 {
+		t.Errorf("expected 3 misses in a window of 8, got %d of %d", info.missed, info.window)
+	}
+
+	// blocks are recorded by the websocket handlers while the backend is queried
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			cc.resultsMux.Lock()
+			cc.blocksResults = append([]int{int(Statusmissed)}, cc.blocksResults[:len(cc.blocksResults)-1]...)
+			cc.resultsMux.Unlock()
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		if info := cc.observedSigning(); info.window != 8 {
+			t.Fatalf("expected a window of 8, got %d", info.window)
+		}
+	}
+	<-done
+	if info := cc.observedSigning(); info.missed != 8 {
+		t.Errorf("expected every block missed, got %d", info.missed)
+	}
+}
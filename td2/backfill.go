@@ -0,0 +1,231 @@
+package tenderduty
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+const (
+	backfillLimit = 100 // the most blocks that will be fetched to fill a gap, older heights are left empty
+	blockQueue    = 20  // new blocks waiting for the sequencer, more are dropped and backfilled
+)
+
+// sequenceBlocks decodes the blocks from every websocket once, drops duplicates, and sends them to each validator's
+// handlers in order. Heights skipped since the last block, because the websocket reconnected or events were dropped,
+// are fetched and their statuses sent first. Backfilled blocks are fetched using the RPC client, so only signed,
+// proposed, or missed can be determined for them.
+func (cc *ChainConfig) sequenceBlocks(ctx context.Context, dec *eventDecoder, blocks chan *WsReply, handlers []*eventChans) {
+	var last int64
+	for _, v := range cc.validators() {
+		if v.lastBlockNum > last {
+			last = v.lastBlockNum
+		}
+	}
+	for {
+		select {
+		case reply := <-blocks:
+			b, err := dec.block(reply.Value())
+			if err != nil {
+				l("could not decode block", err)
+				continue
+			}
+			height := b.Block.Header.Height.val()
+			if height <= last {
+				continue
+			}
+			for _, missed := range backfillBlocks(ctx, cc.ChainId, last, height, cc.fetchBlock) {
+				for _, h := range handlers {
+					upd := StatusUpdate{Height: missed.Height, Status: signedStatus(missed, h.address), Final: true, Time: missed.Time}
+					select {
+					case h.results <- upd:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+			last = height
+			for _, h := range handlers {
+				select {
+				case h.blocks <- b:
+				case <-ctx.Done():
+					return
+				}
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// backfillBlocks fetches the blocks skipped between the last recorded height and a new block. At most backfillLimit
+// blocks are fetched, and it stops at the first error, the blocks fetched before it are returned.
+func backfillBlocks(ctx context.Context, chainId string, last, height int64, fetch func(context.Context, int64) (*types.Block, error)) []*types.Block {
+	if last == 0 || height <= last+1 {
+		return nil
+	}
+	from := last + 1
+	if height-from > backfillLimit {
+		l(fmt.Sprintf("⏪ %-12s %d blocks were missed, only backfilling the last %d", chainId, height-from, backfillLimit))
+		from = height - backfillLimit
+	}
+	fetched := make([]*types.Block, 0, height-from)
+	for h := from; h < height; h++ {
+		b, err := fetch(ctx, h)
+		if err != nil {
+			l(fmt.Sprintf("⏪ %-12s could not backfill block %d: %v", chainId, h, err))
+			break
+		}
+		fetched = append(fetched, b)
+	}
+	if len(fetched) > 0 {
+		l(fmt.Sprintf("⏪ %-12s backfilled blocks %d to %d", chainId, from, fetched[len(fetched)-1].Height))
+	}
+	return fetched
+}
+
+// fetchBlock gets a block using the RPC client, it is verified if the light client is enabled.
+func (cc *ChainConfig) fetchBlock(ctx context.Context, height int64) (*types.Block, error) {
+	client := cc.client
+	if client == nil {
+		return nil, errors.New("no rpc client")
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	b, err := client.Block(ctx, &height)
+	if err != nil {
+		return nil, err
+	}
+	if err = cc.checkBlock(client.Remote(), b.Block); err != nil {
+		if isUnverifiable(err) {
+			cc.unverifiableNode(client.Remote(), err)
+		}
+		return nil, err
+	}
+	return b.Block, nil
+}
+
+// signedStatus is the validator's status for a block: proposed, signed (included in the last commit,) or missed.
+func signedStatus(b *types.Block, address string) StatusType {
+	if b.ProposerAddress.String() == address {
+		return StatusProposed
+	}
+	if b.LastCommit == nil {
+		return Statusmissed
+	}
+	for _, sig := range b.LastCommit.Signatures {
+		if sig.ValidatorAddress.String() == address {
+			return StatusSigned
+		}
+	}
+	return Statusmissed
+}
+
+const (
+	bootstrapWorkers = 4  // concurrent block requests when loading history at startup
+	bootstrapRate    = 20 // maximum block requests per second when loading history at startup
+)
+
+// bootstrap fills the dashboard grid and counters with recent blocks when there is no saved state, so that it isn't
+// empty until enough new blocks have been seen. The blocks are historical, they don't count towards consecutive
+// misses and never raise alerts. Followers sharing the chain are filled from the same blocks.
+func (cc *ChainConfig) bootstrap() {
+	vals := make(map[*ChainConfig]string)
+	for _, v := range cc.validators() {
+		if v.lastBlockNum != 0 || v.valInfo == nil || v.valInfo.Conspub == nil || !v.valInfo.Bonded || !v.emptyGrid() {
+			continue
+		}
+		vals[v] = strings.ToUpper(hex.EncodeToString(v.valInfo.Conspub))
+	}
+	if len(vals) == 0 || cc.client == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	status, err := cc.client.Status(ctx)
+	if err != nil {
+		l(fmt.Sprintf("⏪ %-12s could not load block history: %v", cc.ChainId, err))
+		return
+	}
+	latest := status.SyncInfo.LatestBlockHeight
+	from := latest - int64(showBLocks) + 1
+	if from < 1 {
+		from = 1
+	}
+	l(fmt.Sprintf("⏪ %-12s loading block history from %d to %d", cc.ChainId, from, latest))
+
+	// statuses has the result for each validator by height
+	statuses := make(map[int64]map[*ChainConfig]StatusType)
+	mux := sync.Mutex{}
+	heights := make(chan int64)
+	wg := sync.WaitGroup{}
+	for i := 0; i < bootstrapWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for h := range heights {
+				b, err := cc.fetchBlock(ctx, h)
+				if err != nil {
+					continue
+				}
+				result := make(map[*ChainConfig]StatusType)
+				for v, address := range vals {
+					result[v] = signedStatus(b, address)
+				}
+				mux.Lock()
+				statuses[h] = result
+				mux.Unlock()
+			}
+		}()
+	}
+	limit := time.NewTicker(time.Second / bootstrapRate)
+	for h := latest; h >= from && ctx.Err() == nil; h-- {
+		<-limit.C
+		heights <- h
+	}
+	limit.Stop()
+	close(heights)
+	wg.Wait()
+
+	for v := range vals {
+		var n int
+		for h := latest; h >= from; h-- {
+			status, ok := statuses[h][v]
+			if !ok {
+				continue
+			}
+			v.resultsMux.Lock()
+			v.blocksResults[latest-h] = int(status)
+			v.historical[latest-h] = true
+			v.resultsMux.Unlock()
+			n += 1
+			switch status {
+			case StatusProposed:
+				v.statTotalProps += 1
+				v.statTotalSigns += 1
+			case StatusSigned:
+				v.statTotalSigns += 1
+			default:
+				v.statTotalMiss += 1
+			}
+		}
+		v.lastBlockNum = latest
+		l(fmt.Sprintf("⏪ %-12s loaded %d historical blocks for %s", v.ChainId, n, v.valInfo.Moniker))
+	}
+}
+
+// emptyGrid is true if no blocks have been recorded, ie there was no saved state.
+func (cc *ChainConfig) emptyGrid() bool {
+	for _, status := range cc.blocksResults {
+		if status != -1 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,108 @@
+package tenderduty
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestBackfillBlocks(t *testing.T) {
+	fetched := make(map[int64]int)
+	fetch := func(fail int64) func(context.Context, int64) (*types.Block, error) {
+		return func(_ context.Context, height int64) (*types.Block, error) {
+			fetched[height] += 1
+			if height == fail {
+				return nil, errors.New("unavailable")
+			}
+			return &types.Block{Header: types.Header{Height: height}}, nil
+		}
+	}
+	heights := func(blocks []*types.Block) []int64 {
+		h := make([]int64, 0)
+		for _, b := range blocks {
+			h = append(h, b.Height)
+		}
+		return h
+	}
+	for name, tc := range map[string]struct {
+		last, height, fail int64
+		first, n           int64
+	}{
+		"no gap":       {last: 10, height: 11},
+		"duplicate":    {last: 10, height: 10},
+		"no history":   {last: 0, height: 500},
+		"gap":          {last: 10, height: 14, first: 11, n: 3},
+		"capped":       {last: 1, height: 500, first: 400, n: backfillLimit},
+		"stop on fail": {last: 10, height: 20, fail: 13, first: 11, n: 2},
+	} {
+		fetched = make(map[int64]int)
+		blocks := backfillBlocks(context.Background(), "test-1", tc.last, tc.height, fetch(tc.fail))
+		got := heights(blocks)
+		if int64(len(got)) != tc.n || (tc.n > 0 && (got[0] != tc.first || got[len(got)-1] != tc.first+tc.n-1)) {
+			t.Errorf("%s: unexpected heights %v", name, got)
+		}
+		if tc.fail != 0 && fetched[tc.fail+1] != 0 {
+			t.Errorf("%s: kept fetching after an error", name)
+		}
+		for h, n := range fetched {
+			if n > 1 {
+				t.Errorf("%s: height %d was fetched %d times", name, h, n)
+			}
+		}
+	}
+}
+
+func TestSignedStatus(t *testing.T) {
+	ours := types.Address{0x1d, 0x8c}
+	other := types.Address{0xe8, 0x77}
+	commit := func(addrs ...types.Address) *types.Commit {
+		c := &types.Commit{}
+		for _, a := range addrs {
+			c.Signatures = append(c.Signatures, types.CommitSig{BlockIDFlag: types.BlockIDFlagCommit, ValidatorAddress: a})
+		}
+		// absent validators have an empty address
+		c.Signatures = append(c.Signatures, types.NewCommitSigAbsent())
+		return c
+	}
+	for name, tc := range map[string]struct {
+		block *types.Block
+		want  StatusType
+	}{
+		"proposed":           {&types.Block{Header: types.Header{ProposerAddress: ours}, LastCommit: commit(other)}, StatusProposed},
+		"proposed no commit": {&types.Block{Header: types.Header{ProposerAddress: ours}}, StatusProposed},
+		"signed":             {&types.Block{Header: types.Header{ProposerAddress: other}, LastCommit: commit(other, ours)}, StatusSigned},
+		"missed":             {&types.Block{Header: types.Header{ProposerAddress: other}, LastCommit: commit(other)}, Statusmissed},
+		"no commit":          {&types.Block{Header: types.Header{ProposerAddress: other}}, Statusmissed},
+	} {
+		if got := signedStatus(tc.block, ours.String()); got != tc.want {
+			t.Errorf("%s: expected %d, got %d", name, tc.want, got)
+		}
+	}
+}
+
+func TestSequenceBlocks(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cc := &ChainConfig{ChainId: "test-1"}
+	handlers := []*eventChans{{blocks: make(chan *rawBlock, 2)}, {blocks: make(chan *rawBlock, 2)}}
+	blocks := make(chan *WsReply)
+	go cc.sequenceBlocks(ctx, eventDecoders[tendermint034], blocks, handlers)
+	// the same block from three websockets, the last send only completes once the second has been handled.
+	reply := loadReply(t, tendermint034, "new_block.json")
+	blocks <- reply
+	blocks <- reply
+	blocks <- reply
+	for i, h := range handlers {
+		if b := <-h.blocks; b.Block.Header.Height.val() != 1234 {
+			t.Errorf("handler %d got block %d", i, b.Block.Header.Height.val())
+		}
+	}
+	cancel()
+	for i, h := range handlers {
+		if len(h.blocks) != 0 {
+			t.Errorf("handler %d got a duplicate block", i)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package tenderduty
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	bank "github.com/cosmos/cosmos-sdk/x/bank/types"
+)
+
+// balanceRefresh is how often account balances are checked.
+const balanceRefresh = 5 * time.Minute
+
+// AccountConfig is an operator wallet, such as a price-feeder, relayer, or governance voting account, that needs
+// to keep enough funds to pay for gas.
+type AccountConfig struct {
+	// Address is the bech32 account address to monitor
+	Address string `yaml:"address"`
+	// Label is a friendly name used in alerts, ie "price-feeder"
+	Label string `yaml:"label"`
+	// MinBalance maps a denom to the minimum balance (in the base denom) before an alert is sent
+	MinBalance map[string]float64 `yaml:"min_balance"`
+}
+
+func (ac *AccountConfig) name() string {
+	if ac.Label != "" {
+		return fmt.Sprintf("%s (%s)", ac.Label, ac.Address)
+	}
+	return ac.Address
+}
+
+// getBalances queries /cosmos.bank.v1beta1.Query/AllBalances for an account.
+func getBalances(ctx context.Context, cc *ChainConfig, address string) (map[string]float64, error) {
+	q := bank.QueryAllBalancesRequest{Address: address}
+	b, err := q.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := cc.query(ctx, "/cosmos.bank.v1beta1.Query/AllBalances", b)
+	if err != nil {
+		return nil, fmt.Errorf("could not query balances for %s: %v", address, err)
+	}
+	balances := &bank.QueryAllBalancesResponse{}
+	err = balances.Unmarshal(resp)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]float64)
+	for _, coin := range balances.Balances {
+		result[coin.Denom] = coinToFloat(coin.Amount)
+	}
+	return result, nil
+}
+
+// checkBalances refreshes the balances for all configured accounts, exports them to prometheus, and builds the set of
+// low balance alarms consumed by watch(). The alarm map is replaced rather than updated since watch() reads it.
+func (cc *ChainConfig) checkBalances() error {
+	if len(cc.Accounts) == 0 || cc.client == nil {
+		return nil
+	}
+	if !cc.lastBalanceCheck.IsZero() && time.Since(cc.lastBalanceCheck) < balanceRefresh {
+		return nil
+	}
+	cc.lastBalanceCheck = time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	lowBalances := make(map[string]bool)
+	var failed []string
+	for _, account := range cc.Accounts {
+		balances, err := getBalances(ctx, cc, account.Address)
+		if err != nil {
+			failed = append(failed, account.Address)
+			continue
+		}
+		if td.Prom {
+			for denom, amount := range balances {
+				td.statsChan <- cc.mkAccountUpdate(account.Address, denom, amount)
+			}
+		}
+		denoms := make([]string, 0, len(account.MinBalance))
+		for denom := range account.MinBalance {
+			denoms = append(denoms, denom)
+		}
+		sort.Strings(denoms)
+		for _, denom := range denoms {
+			msg := fmt.Sprintf("%s balance is below %.0f%s on %s", account.name(), account.MinBalance[denom], denom, cc.ChainId)
+			lowBalances[msg] = balances[denom] < account.MinBalance[denom]
+			if lowBalances[msg] {
+				l(fmt.Sprintf("💸 %-12s %s has %.0f%s", cc.ChainId, account.name(), balances[denom], denom))
+			}
+		}
+	}
+	cc.lowBalances = lowBalances
+	if len(failed) > 0 {
+		return fmt.Errorf("could not get balances for %v", failed)
+	}
+	return nil
+}
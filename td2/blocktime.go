@@ -0,0 +1,75 @@
+package tenderduty
+
+import (
+	"time"
+)
+
+const (
+	// blockTimeWindow is how many block intervals are used for the rolling average.
+	blockTimeWindow = 20
+	// blockTimeLearnRate is the weight given to each new interval when learning a chain's baseline block time.
+	blockTimeLearnRate = 0.01
+)
+
+// blockTimes keeps a rolling average of block intervals, based on the block header times, and a baseline to compare
+// against. If no baseline is configured it is learned slowly from the observed intervals.
+type blockTimes struct {
+	lastHeight int64
+	lastTime   time.Time
+	intervals  []float64
+	average    float64
+	baseline   float64
+}
+
+// add records a new block header, returning the interval in seconds since the previous block and whether an interval
+// could be calculated. If blocks were skipped (for example during a websocket reconnect) the interval is averaged
+// over the missing heights.
+func (bt *blockTimes) add(height int64, headerTime time.Time, configuredBaseline, multiple float64) (interval float64, ok bool) {
+	defer func() {
+		bt.lastHeight, bt.lastTime = height, headerTime
+	}()
+	if bt.lastTime.IsZero() || headerTime.IsZero() || height <= bt.lastHeight {
+		return 0, false
+	}
+	interval = headerTime.Sub(bt.lastTime).Seconds() / float64(height-bt.lastHeight)
+	if interval <= 0 {
+		return 0, false
+	}
+
+	bt.intervals = append(bt.intervals, interval)
+	if len(bt.intervals) > blockTimeWindow {
+		bt.intervals = bt.intervals[len(bt.intervals)-blockTimeWindow:]
+	}
+	var total float64
+	for _, i := range bt.intervals {
+		total += i
+	}
+	bt.average = total / float64(len(bt.intervals))
+
+	switch {
+	case configuredBaseline > 0:
+		bt.baseline = configuredBaseline
+	case bt.baseline == 0:
+		bt.baseline = interval
+	case interval <= defaultMultiple(multiple)*bt.baseline:
+		// don't let slow blocks drag the learned baseline upwards
+		bt.baseline += blockTimeLearnRate * (interval - bt.baseline)
+	}
+	return interval, true
+}
+
+// degraded returns true if the rolling average is above multiple times the baseline. It will not report a degraded
+// state until the window has filled. A multiple of zero uses a default of two times the baseline.
+func (bt *blockTimes) degraded(multiple float64) bool {
+	if len(bt.intervals) < blockTimeWindow || bt.baseline == 0 {
+		return false
+	}
+	return bt.average > defaultMultiple(multiple)*bt.baseline
+}
+
+func defaultMultiple(multiple float64) float64 {
+	if multiple <= 0 {
+		return 2
+	}
+	return multiple
+}
@@ -0,0 +1,59 @@
+package tenderduty
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBlockTimes(t *testing.T) {
+	bt := &blockTimes{}
+	start := time.Now()
+	height := int64(100)
+
+	if _, ok := bt.add(height, start, 0, 2); ok {
+		t.Error("first block should not produce an interval")
+	}
+
+	// a steady 6 second chain
+	for i := 1; i <= blockTimeWindow; i++ {
+		interval, ok := bt.add(height+int64(i), start.Add(time.Duration(i)*6*time.Second), 0, 2)
+		if !ok || interval != 6 {
+			t.Fatalf("expected a 6s interval, got %v %v", interval, ok)
+		}
+	}
+	if bt.baseline != 6 || bt.average != 6 {
+		t.Errorf("expected a 6s baseline and average, got %v and %v", bt.baseline, bt.average)
+	}
+	if bt.degraded(2) {
+		t.Error("steady block times should not be degraded")
+	}
+
+	// a gap in heights is averaged over the missing blocks
+	height += blockTimeWindow
+	last := start.Add(blockTimeWindow * 6 * time.Second)
+	if interval, _ := bt.add(height+10, last.Add(60*time.Second), 0, 2); interval != 6 {
+		t.Errorf("expected skipped heights to average to 6s, got %v", interval)
+	}
+	height += 10
+	last = last.Add(60 * time.Second)
+
+	// slow down to 15 second blocks
+	for i := 1; i <= blockTimeWindow; i++ {
+		bt.add(height+int64(i), last.Add(time.Duration(i)*15*time.Second), 0, 2)
+	}
+	if !bt.degraded(2) {
+		t.Errorf("expected degraded block times, average %v baseline %v", bt.average, bt.baseline)
+	}
+	if bt.baseline > 7 {
+		t.Errorf("baseline should not follow a degraded chain, got %v", bt.baseline)
+	}
+	if bt.degraded(3) {
+		t.Error("should not be degraded with a 3x multiple")
+	}
+
+	// a configured baseline always wins
+	bt.add(height+blockTimeWindow+1, last.Add((blockTimeWindow+1)*15*time.Second), 10, 2)
+	if bt.baseline != 10 || bt.degraded(2) {
+		t.Errorf("expected the configured baseline to be used, got %v", bt.baseline)
+	}
+}
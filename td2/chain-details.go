@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"errors"
 	"io"
-	"net/http"
 	"strings"
 	"sync"
 	"time"
@@ -36,7 +35,7 @@ func (vo *valoperOverrides) getAltPrefix(oper string) (prefix string, ok bool) {
 	return altValopers.Prefixes[split[0]], altValopers.Prefixes[split[0]] != ""
 }
 
-// cosmosPaths holds a mapping useful for finding public nodes from the cosmos directory from eco_stake
+// cosmosPaths maps chain ids to their directory in the chain registry, for finding public nodes
 // it will be refreshed periodically.
 var cosmosPaths = map[string]string{
 	"Antora":                     "idep",
@@ -110,7 +109,6 @@ var cosmosPaths = map[string]string{
 var pathMux sync.Mutex
 
 const registryJson = "https://chains.cosmos.directory/"
-const publicRpcUrl = "https://rpc.cosmos.directory:443/"
 
 // a trimmed down version only holding the info we need to create a lookup map
 type registryResults struct {
@@ -120,9 +118,9 @@ type registryResults struct {
 	} `json:"chains"`
 }
 
-// refreshRegistry updates the path map for public RPC endpoints for @eco_stake's public RPC proxy
+// refreshRegistry updates the path map for public RPC endpoints using @eco_stake's cosmos.directory
 func refreshRegistry() error {
-	res, err := http.Get(registryJson)
+	res, err := proxyClient().Get(registryJson)
 	if err != nil {
 		return err
 	}
@@ -150,8 +148,9 @@ func refreshRegistry() error {
 	return nil
 }
 
-func getRegistryUrl(chainid string) (url string, ok bool) {
+// getRegistryPath returns the chain's directory in the chain registry.
+func getRegistryPath(chainid string) (path string, ok bool) {
 	pathMux.Lock()
 	defer pathMux.Unlock()
-	return publicRpcUrl + cosmosPaths[chainid], cosmosPaths[chainid] != ""
+	return cosmosPaths[chainid], cosmosPaths[chainid] != ""
 }
@@ -0,0 +1,214 @@
+package tenderduty
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	slashing "github.com/cosmos/cosmos-sdk/x/slashing/types"
+	rpcclient "github.com/tendermint/tendermint/rpc/client"
+	rpchttp "github.com/tendermint/tendermint/rpc/client/http"
+)
+
+// consistencyNodes is the number of nodes needed to decide which node is wrong when they disagree.
+const consistencyNodes = 3
+
+// nodeView is what a single node reports at the height being compared.
+type nodeView struct {
+	node   *NodeConfig
+	client *rpchttp.HTTP
+	height int64            // the node's latest height
+	hash   string           // block hash at the compared height
+	missed map[string]int64 // missed blocks counter by valcons address
+}
+
+// usable is false if the node is down, or disagrees with the other nodes for the chain.
+func (node *NodeConfig) usable() bool {
+	return !node.down && node.inconsistent == ""
+}
+
+// outliers finds the values that differ from the value reported by a majority of nodes. Values are keyed by the
+// node's URL. Nothing is returned if there aren't enough nodes, or no value has a majority.
+func outliers(values map[string]string) (expected string, urls []string) {
+	if len(values) < consistencyNodes {
+		return "", nil
+	}
+	counts := make(map[string]int)
+	for _, v := range values {
+		counts[v] += 1
+	}
+	for v, n := range counts {
+		if n*2 > len(values) {
+			expected = v
+		}
+	}
+	if expected == "" {
+		return "", nil
+	}
+	for u, v := range values {
+		if v != expected {
+			urls = append(urls, u)
+		}
+	}
+	sort.Strings(urls)
+	return
+}
+
+// signingAddresses returns the consensus addresses used to query the slashing module for each validator on the chain.
+func (cc *ChainConfig) signingAddresses() []string {
+	addrs := make([]string, 0)
+	if cc.Backend == backendConsensus {
+		return addrs
+	}
+	for _, v := range cc.validators() {
+		if v.valInfo != nil && v.valInfo.Valcons != "" {
+			addrs = append(addrs, v.valInfo.Valcons)
+		}
+	}
+	return addrs
+}
+
+// fetch gets the block hash, and the missed blocks counters for the validators, at a height.
+func (v *nodeView) fetch(ctx context.Context, height int64, valcons []string) {
+	if commit, err := v.client.Commit(ctx, &height); err == nil && commit.Header != nil {
+		v.hash = commit.Header.Hash().String()
+	}
+	v.missed = make(map[string]int64)
+	for _, addr := range valcons {
+		b, err := (&slashing.QuerySigningInfoRequest{ConsAddress: addr}).Marshal()
+		if err != nil {
+			continue
+		}
+		resp, err := v.client.ABCIQueryWithOptions(ctx, "/cosmos.slashing.v1beta1.Query/SigningInfo", b, rpcclient.ABCIQueryOptions{Height: height})
+		if err != nil || resp.Response.Code != 0 || len(resp.Response.Value) == 0 {
+			continue
+		}
+		if missed, _, err := decodeSigningInfo(resp.Response.Value); err == nil {
+			v.missed[addr] = missed
+		}
+	}
+}
+
+// checkConsistency compares the block hash and the validators' missed block counters reported by each healthy node
+// at the same height. A node that disagrees with the majority is probably on a fork, or is lying, and is not used
+// for monitoring until it agrees again. Only one check runs at a time for a chain, a check that is started while
+// another is running is skipped.
+func (cc *ChainConfig) checkConsistency() {
+	if !atomic.CompareAndSwapInt32(&cc.checkingNodes, 0, 1) {
+		l(fmt.Sprintf("🍴 %-12s skipping the consistency check, the last one is still running", cc.ChainId))
+		return
+	}
+	defer atomic.StoreInt32(&cc.checkingNodes, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	views := make([]*nodeView, 0)
+	mux := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for _, node := range cc.Nodes {
+		if node.down {
+			continue
+		}
+		wg.Add(1)
+		go func(node *NodeConfig) {
+			defer wg.Done()
+			c, err := cc.newClient(node.Url)
+			if err != nil {
+				return
+			}
+			status, err := c.Status(ctx)
+			if err != nil || status.SyncInfo.CatchingUp {
+				return
+			}
+			mux.Lock()
+			views = append(views, &nodeView{node: node, client: c, height: status.SyncInfo.LatestBlockHeight})
+			mux.Unlock()
+		}(node)
+	}
+	wg.Wait()
+	if len(views) < consistencyNodes {
+		// without a majority the earlier results can't be confirmed, they are cleared rather than kept forever.
+		for _, node := range cc.Nodes {
+			if node.inconsistent != "" {
+				l(fmt.Sprintf("🍴 %-12s only %d nodes could be compared, no longer treating %s as inconsistent", cc.ChainId, len(views), node.Url))
+				node.inconsistent = ""
+			}
+		}
+		return
+	}
+
+	// compare at the latest height every node has
+	height := views[0].height
+	for _, v := range views {
+		if v.height < height {
+			height = v.height
+		}
+	}
+	valcons := cc.signingAddresses()
+	for _, v := range views {
+		wg.Add(1)
+		go func(v *nodeView) {
+			defer wg.Done()
+			v.fetch(ctx, height, valcons)
+		}(v)
+	}
+	wg.Wait()
+
+	// what disagrees is used for the alarm message, the details with heights and values are only logged.
+	badHash := make(map[string]bool)
+	badMissed := make(map[string]bool)
+	details := make(map[string][]string)
+	hashes := make(map[string]string)
+	for _, v := range views {
+		if v.hash != "" {
+			hashes[v.node.Url] = v.hash
+		}
+	}
+	expected, bad := outliers(hashes)
+	for _, u := range bad {
+		badHash[u] = true
+		details[u] = append(details[u], fmt.Sprintf("block %d hash %s, expected %s", height, hashes[u], expected))
+	}
+	for _, addr := range valcons {
+		counters := make(map[string]string)
+		for _, v := range views {
+			if missed, ok := v.missed[addr]; ok {
+				counters[v.node.Url] = strconv.FormatInt(missed, 10)
+			}
+		}
+		expected, bad = outliers(counters)
+		for _, u := range bad {
+			badMissed[u] = true
+			details[u] = append(details[u], fmt.Sprintf("%s missed blocks counter %s at height %d, expected %s", addr, counters[u], height, expected))
+		}
+	}
+
+	for _, v := range views {
+		problems := make([]string, 0)
+		if badHash[v.node.Url] {
+			problems = append(problems, "block hash")
+		}
+		if badMissed[v.node.Url] {
+			problems = append(problems, "missed blocks counter")
+		}
+		msg := ""
+		if len(problems) > 0 {
+			msg = fmt.Sprintf("node %s disagrees with the majority of nodes on %s: %s", v.node.Url, cc.ChainId, strings.Join(problems, ", "))
+			l(fmt.Sprintf("🍴 %-12s node %s disagrees with the majority of nodes: %s", cc.ChainId, v.node.Url, strings.Join(details[v.node.Url], "; ")))
+		} else if v.node.inconsistent != "" {
+			l(fmt.Sprintf("🍴 %-12s node %s agrees with the majority of nodes again", cc.ChainId, v.node.Url))
+		}
+		if msg != "" && v.node.inconsistent == "" && v.node.Url == cc.activeUrl() {
+			// stop using this node's events, newRpc skips it when monitoring restarts.
+			l(fmt.Sprintf("🍴 %-12s restarting monitoring without %s", cc.ChainId, v.node.Url))
+			cc.requestRestart()
+		}
+		v.node.inconsistent = msg
+	}
+}
@@ -0,0 +1,52 @@
+package tenderduty
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOutliers(t *testing.T) {
+	for name, tc := range map[string]struct {
+		values   map[string]string
+		expected string
+		outliers []string
+	}{
+		"agree":      {map[string]string{"a": "1", "b": "1", "c": "1"}, "1", nil},
+		"one fork":   {map[string]string{"a": "1", "b": "2", "c": "1"}, "1", []string{"b"}},
+		"two forks":  {map[string]string{"a": "1", "b": "2", "c": "1", "d": "3", "e": "1"}, "1", []string{"b", "d"}},
+		"too few":    {map[string]string{"a": "1", "b": "2"}, "", nil},
+		"no quorum":  {map[string]string{"a": "1", "b": "2", "c": "3"}, "", nil},
+		"even split": {map[string]string{"a": "1", "b": "2", "c": "1", "d": "2"}, "", nil},
+	} {
+		expected, outliers := outliers(tc.values)
+		if expected != tc.expected || !reflect.DeepEqual(outliers, tc.outliers) {
+			t.Errorf("%s: expected %q %v, got %q %v", name, tc.expected, tc.outliers, expected, outliers)
+		}
+	}
+}
+
+func TestCheckConsistencyClearsStaleFlags(t *testing.T) {
+	a := statusServer("test-1", false, 0)
+	defer a.Close()
+	b := statusServer("test-1", false, 0)
+	defer b.Close()
+	stale := "node disagrees with the majority of nodes"
+	cc := &ChainConfig{ChainId: "test-1", Nodes: []*NodeConfig{{Url: a.URL, inconsistent: stale}, {Url: b.URL}}}
+
+	// a check that is still running causes the next one to be skipped
+	cc.checkingNodes = 1
+	cc.checkConsistency()
+	if cc.Nodes[0].inconsistent != stale {
+		t.Error("a check ran while another was running")
+	}
+
+	// two nodes can't be compared, the earlier result is cleared
+	cc.checkingNodes = 0
+	cc.checkConsistency()
+	if cc.Nodes[0].inconsistent != "" {
+		t.Error("the stale inconsistent flag was not cleared")
+	}
+	if cc.checkingNodes != 0 {
+		t.Error("the running flag was not reset")
+	}
+}
@@ -0,0 +1,105 @@
+package tenderduty
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/cosmos/cosmos-sdk/types/bech32"
+	rpchttp "github.com/tendermint/tendermint/rpc/client/http"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// consumerAddrQuery is the provider's query for the consensus address a validator uses on a consumer chain.
+const consumerAddrQuery = "/interchain_security.ccv.provider.v1.Query/QueryValidatorConsumerAddr"
+
+// ConsumerConfig enables Interchain Security consumer chain mode. Consumer chains have no staking module, so the
+// validator is looked up on the provider chain, and the consensus key it signs with may have been assigned using
+// the provider's AssignConsumerKey.
+type ConsumerConfig struct {
+	// Enabled treats the valoper_address as an operator address on the provider chain
+	Enabled bool `yaml:"enabled"`
+	// ProviderNodes are RPC endpoints for the provider chain, they are tried in order
+	ProviderNodes []string `yaml:"provider_nodes"`
+	// ValconsPrefix is the consumer chain's bech32 consensus address prefix, ie "neutronvalcons". It is needed to
+	// query the consumer's slashing module. If not set, the signing window is tracked by observing blocks.
+	ValconsPrefix string `yaml:"valcons_prefix"`
+}
+
+// valconsPrefix derives the bech32 prefix for a consensus address from an operator address.
+func valconsPrefix(valoper string) (string, error) {
+	split := strings.Split(valoper, "valoper")
+	if len(split) == 2 {
+		return split[0] + "valcons", nil
+	}
+	if pre, ok := altValopers.getAltPrefix(valoper); ok {
+		return pre, nil
+	}
+	return "", errors.New("❓ could not determine bech32 prefix from valoper address: " + valoper)
+}
+
+// getConsumerAddr asks the provider for the consumer chain consensus address assigned to a validator. The address is
+// encoded with the provider's prefix, so only the bytes are returned. If no key has been assigned, the validator
+// signs with the same key on both chains.
+func getConsumerAddr(ctx context.Context, query queryFunc, chainId, providerValcons string, providerAddr []byte) ([]byte, error) {
+	var req []byte
+	req = protowire.AppendTag(req, 1, protowire.BytesType)
+	req = protowire.AppendString(req, chainId)
+	req = protowire.AppendTag(req, 2, protowire.BytesType)
+	req = protowire.AppendString(req, providerValcons)
+	resp, err := query(ctx, consumerAddrQuery, req)
+	if err != nil {
+		return nil, err
+	}
+	addr, err := findBytes(resp, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(addr) == 0 {
+		return providerAddr, nil
+	}
+	_, bz, err := bech32.DecodeAndConvert(string(addr))
+	if err != nil {
+		return nil, errors.New("could not decode consumer address " + string(addr))
+	}
+	return bz, nil
+}
+
+// getConsumerVal looks up the validator on the provider chain, and resolves the consensus address it uses on this
+// consumer chain. The returned valcons is empty if the consumer's prefix is not known.
+func (cc *ChainConfig) getConsumerVal(ctx context.Context) (conspub []byte, valcons, moniker string, jailed, bonded bool, err error) {
+	prefix, err := valconsPrefix(cc.ValAddress)
+	if err != nil {
+		return
+	}
+	err = errors.New("no provider nodes available for " + cc.ChainId)
+	for _, node := range cc.Consumer.ProviderNodes {
+		var client *rpchttp.HTTP
+		client, err = cc.newClient(node)
+		if err != nil {
+			continue
+		}
+		var providerAddr []byte
+		providerAddr, moniker, jailed, bonded, err = getVal(ctx, abciQuery(client), cc.ValAddress)
+		if err != nil {
+			continue
+		}
+		var providerValcons string
+		providerValcons, err = bech32.ConvertAndEncode(prefix, providerAddr[:20])
+		if err != nil {
+			return
+		}
+		conspub, err = getConsumerAddr(ctx, abciQuery(client), cc.ChainId, providerValcons, providerAddr)
+		if err != nil {
+			continue
+		}
+		break
+	}
+	if err != nil {
+		return
+	}
+	if cc.Consumer.ValconsPrefix != "" {
+		valcons, err = bech32.ConvertAndEncode(cc.Consumer.ValconsPrefix, conspub[:20])
+	}
+	return
+}
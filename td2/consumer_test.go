@@ -0,0 +1,61 @@
+package tenderduty
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cosmos/cosmos-sdk/types/bech32"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestGetConsumerAddr(t *testing.T) {
+	providerAddr, consumerAddr := bytes.Repeat([]byte{0x1d}, 20), bytes.Repeat([]byte{0x8c}, 20)
+	assigned, err := bech32.ConvertAndEncode("cosmosvalcons", consumerAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the provider replies with QueryValidatorConsumerAddrResponse, the consumer address is field 1
+	var resp []byte
+	query := func(ctx context.Context, path string, req []byte) ([]byte, error) {
+		if path != consumerAddrQuery {
+			t.Errorf("unexpected query %s", path)
+		}
+		chainId, err := findBytes(req, 1)
+		if err != nil || string(chainId) != "neutron-1" {
+			t.Errorf("expected chain_id neutron-1, got %q %v", chainId, err)
+		}
+		valcons, err := findBytes(req, 2)
+		if err != nil || string(valcons) != "cosmosvalcons1provider" {
+			t.Errorf("expected provider_address cosmosvalcons1provider, got %q %v", valcons, err)
+		}
+		return resp, nil
+	}
+
+	for _, test := range []struct {
+		name   string
+		resp   []byte
+		expect []byte
+		err    bool
+	}{
+		{name: "assigned key", resp: protowire.AppendString(protowire.AppendTag(nil, 1, protowire.BytesType), assigned), expect: consumerAddr},
+		{name: "no assigned key", resp: []byte{}, expect: providerAddr},
+		{name: "empty address", resp: protowire.AppendString(protowire.AppendTag(nil, 1, protowire.BytesType), ""), expect: providerAddr},
+		{name: "invalid address", resp: protowire.AppendString(protowire.AppendTag(nil, 1, protowire.BytesType), "not bech32"), err: true},
+		{name: "truncated", resp: protowire.AppendTag(nil, 1, protowire.BytesType), err: true},
+	} {
+		resp = test.resp
+		addr, err := getConsumerAddr(context.Background(), query, "neutron-1", "cosmosvalcons1provider", providerAddr)
+		if test.err != (err != nil) || !bytes.Equal(addr, test.expect) {
+			t.Errorf("%s: unexpected address %x, error %v", test.name, addr, err)
+		}
+	}
+
+	failing := func(ctx context.Context, path string, req []byte) ([]byte, error) {
+		return nil, errors.New("unknown query path")
+	}
+	if _, err = getConsumerAddr(context.Background(), failing, "neutron-1", "cosmosvalcons1provider", providerAddr); err == nil {
+		t.Error("expected the query error")
+	}
+}
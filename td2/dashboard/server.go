@@ -3,6 +3,7 @@ package dash
 import (
 	"embed"
 	"encoding/json"
+	"fmt"
 	"github.com/gorilla/websocket"
 	"github.com/textileio/go-threads/broadcast"
 	"io/fs"
@@ -18,6 +19,8 @@ var (
 	Content embed.FS
 	rootDir fs.FS
 	rex     = regexp.MustCompile(`\W(https?|tcp|wss?)://.+\w`)
+	// Redact removes any configured secrets from a status's error, if set
+	Redact func(string) string
 )
 
 const logLength = 256
@@ -56,7 +59,15 @@ func Serve(port string, updates chan *ChainStatus, logs chan LogMessage, hideLog
 			case u := <-updates:
 				// try to catch any accidental rpc endpoint leaks
 				if hideLogs && rex.MatchString(u.LastError) {
-					rex.ReplaceAllString(u.LastError, "-redacted-")
+					u.LastError = rex.ReplaceAllString(u.LastError, "-redacted-")
+				}
+				if Redact != nil {
+					u.LastError = Redact(u.LastError)
+				}
+				if hideLogs {
+					for i := range u.Scores {
+						u.Scores[i].Url = fmt.Sprintf("node %d", i+1)
+					}
 				}
 				statusMux.Lock() // probably unnecessary
 				status[u.Name] = u
@@ -16,7 +16,28 @@ type ChainStatus struct {
 	Height       int64  `json:"height"`
 	LastError    string `json:"last_error"`
 
+	Versions   []NodeVersion `json:"versions"`
+	SdkVersion string        `json:"sdk_version"`
+	// Scores are the nodes' health scores, best first
+	Scores []NodeScore `json:"scores"`
+
 	Blocks []int `json:"blocks"`
+	// Historical is which of the Blocks were loaded at startup, rather than seen live
+	Historical []bool `json:"historical"`
+}
+
+// NodeVersion is a distinct combination of versions running on a chain's nodes, and how many nodes are running it.
+type NodeVersion struct {
+	Tendermint string `json:"tendermint"`
+	App        string `json:"app"`
+	Nodes      int    `json:"nodes"`
+}
+
+// NodeScore is a node's health score, the active node is used for queries and the websocket.
+type NodeScore struct {
+	Url    string  `json:"url"`
+	Score  float64 `json:"score"`
+	Active bool    `json:"active"`
 }
 
 type LogMessage struct {
@@ -0,0 +1,214 @@
+package tenderduty
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/query"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+)
+
+const (
+	// delegationRefresh is how often the full delegation set is fetched, it can be expensive on popular validators.
+	delegationRefresh = 5 * time.Minute
+	// delegationMovers is how many of the largest changes are included in an alert.
+	delegationMovers = 5
+)
+
+// delegationState is a snapshot of a validator's delegations used to detect large changes between refreshes.
+type delegationState struct {
+	fetched    time.Time
+	denom      string
+	bonded     float64
+	unbonding  float64
+	delegators map[string]float64
+}
+
+// delegationMove is the change in a single delegator's stake between two snapshots.
+type delegationMove struct {
+	delegator string
+	change    float64
+	unbonding bool
+}
+
+// coinToFloat is lossy, but good enough for deciding if a threshold has been crossed.
+func coinToFloat(i sdk.Int) float64 {
+	if i.IsNil() {
+		return 0
+	}
+	f, _ := new(big.Float).SetInt(i.BigInt()).Float64()
+	return f
+}
+
+// getDelegations pages through the staking module's delegations and unbonding delegations for a validator.
+func getDelegations(ctx context.Context, cc *ChainConfig) (*delegationState, map[string]float64, error) {
+	state := &delegationState{
+		fetched:    time.Now(),
+		delegators: make(map[string]float64),
+	}
+	var next []byte
+	for {
+		q := staking.QueryValidatorDelegationsRequest{
+			ValidatorAddr: cc.ValAddress,
+			Pagination:    &query.PageRequest{Key: next, Limit: 1000},
+		}
+		b, err := q.Marshal()
+		if err != nil {
+			return nil, nil, err
+		}
+		resp, err := cc.query(ctx, "/cosmos.staking.v1beta1.Query/ValidatorDelegations", b)
+		if err != nil {
+			return nil, nil, err
+		}
+		if resp == nil {
+			return nil, nil, errors.New("could not query validator delegations, got empty response")
+		}
+		delegations := &staking.QueryValidatorDelegationsResponse{}
+		err = delegations.Unmarshal(resp)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, d := range delegations.DelegationResponses {
+			amount := coinToFloat(d.Balance.Amount)
+			state.denom = d.Balance.Denom
+			state.bonded += amount
+			state.delegators[d.Delegation.DelegatorAddress] += amount
+		}
+		if delegations.Pagination == nil || len(delegations.Pagination.NextKey) == 0 {
+			break
+		}
+		next = delegations.Pagination.NextKey
+	}
+
+	unbonding := make(map[string]float64)
+	next = nil
+	for {
+		q := staking.QueryValidatorUnbondingDelegationsRequest{
+			ValidatorAddr: cc.ValAddress,
+			Pagination:    &query.PageRequest{Key: next, Limit: 1000},
+		}
+		b, err := q.Marshal()
+		if err != nil {
+			return nil, nil, err
+		}
+		resp, err := cc.query(ctx, "/cosmos.staking.v1beta1.Query/ValidatorUnbondingDelegations", b)
+		if err != nil {
+			return nil, nil, err
+		}
+		if resp == nil {
+			// no unbonding delegations is not an error
+			break
+		}
+		unbonds := &staking.QueryValidatorUnbondingDelegationsResponse{}
+		err = unbonds.Unmarshal(resp)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, u := range unbonds.UnbondingResponses {
+			for _, entry := range u.Entries {
+				amount := coinToFloat(entry.Balance)
+				state.unbonding += amount
+				unbonding[u.DelegatorAddress] += amount
+			}
+		}
+		if unbonds.Pagination == nil || len(unbonds.Pagination.NextKey) == 0 {
+			break
+		}
+		next = unbonds.Pagination.NextKey
+	}
+	return state, unbonding, nil
+}
+
+// diffDelegations compares two snapshots and returns the largest movers, sorted by the size of the change.
+func diffDelegations(prev, cur *delegationState, unbonding, prevUnbonding map[string]float64) []delegationMove {
+	moves := make([]delegationMove, 0)
+	for k, v := range cur.delegators {
+		if change := v - prev.delegators[k]; change != 0 {
+			moves = append(moves, delegationMove{delegator: k, change: change})
+		}
+	}
+	for k, v := range prev.delegators {
+		if _, ok := cur.delegators[k]; !ok {
+			moves = append(moves, delegationMove{delegator: k, change: -v})
+		}
+	}
+	for i := range moves {
+		moves[i].unbonding = moves[i].change < 0 && unbonding[moves[i].delegator] > prevUnbonding[moves[i].delegator]
+	}
+	sort.Slice(moves, func(i, j int) bool {
+		return math.Abs(moves[i].change) > math.Abs(moves[j].change)
+	})
+	if len(moves) > delegationMovers {
+		moves = moves[:delegationMovers]
+	}
+	return moves
+}
+
+// checkDelegations refreshes the delegation snapshot and, if the bonded tokens moved more than the configured
+// threshold, sets cc.delegationAlert which is picked up by watch()
+func (cc *ChainConfig) checkDelegations() error {
+	if !cc.Alerts.DelegationAlerts || cc.client == nil {
+		return nil
+	}
+	if cc.delegations != nil && time.Since(cc.delegations.fetched) < delegationRefresh {
+		return nil
+	}
+	if strings.Contains(cc.ValAddress, "valcons") {
+		return errors.New("delegation alerts require a valoper address")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	state, unbonding, err := getDelegations(ctx, cc)
+	if err != nil {
+		return err
+	}
+	prev, prevUnbonding := cc.delegations, cc.unbonding
+	cc.delegations, cc.unbonding = state, unbonding
+	if prev == nil {
+		l(fmt.Sprintf("⚙️ %-12s %s has %.0f%s bonded from %d delegators", cc.ChainId, cc.valInfo.Moniker, state.bonded, state.denom, len(state.delegators)))
+		return nil
+	}
+
+	change := state.bonded - prev.bonded
+	var pct float64
+	if prev.bonded > 0 {
+		pct = 100 * change / prev.bonded
+	}
+	if (cc.Alerts.DelegationChange <= 0 || math.Abs(change) < cc.Alerts.DelegationChange) &&
+		(cc.Alerts.DelegationPercent <= 0 || math.Abs(pct) < cc.Alerts.DelegationPercent) {
+		cc.delegationAlert = ""
+		return nil
+	}
+
+	direction := "increased"
+	if change < 0 {
+		direction = "decreased"
+	}
+	msg := fmt.Sprintf("%s bonded tokens %s by %.0f%s (%.2f%%) on %s, now %.0f%s",
+		cc.valInfo.Moniker, direction, math.Abs(change), state.denom, pct, cc.ChainId, state.bonded, state.denom)
+	if newUnbonding := state.unbonding - prev.unbonding; newUnbonding > 0 {
+		msg += fmt.Sprintf(", %.0f%s newly unbonding", newUnbonding, state.denom)
+	}
+	moves := diffDelegations(prev, state, unbonding, prevUnbonding)
+	if len(moves) > 0 {
+		msg += "\nlargest changes:"
+		for _, m := range moves {
+			note := ""
+			if m.unbonding {
+				note = " (unbonding)"
+			}
+			msg += fmt.Sprintf("\n - %s %+.0f%s%s", m.delegator, m.change, state.denom, note)
+		}
+	}
+	l("🐳", msg)
+	cc.delegationAlert = msg
+	return nil
+}
@@ -0,0 +1,102 @@
+package tenderduty
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Consensus versions with distinct event encodings. Tendermint v0.34 base64 encodes event attributes, CometBFT v0.37
+// uses plain strings, and v0.38 (ABCI++) replaces the begin and end block results in NewBlock with the finalize_block
+// result and adds vote extensions.
+//
+// Events are decoded into the local types below rather than tendermint's, so the v0.34 module used for the RPC and
+// light clients does not have to match the node's version. Moving that module to CometBFT is out of scope: cosmos-sdk
+// v0.45 requires tendermint v0.34, and the RPC and light client encodings tenderduty uses are the same in v0.37 and
+// v0.38.
+const (
+	tendermint034 = "v0.34"
+	comet037      = "v0.37"
+	comet038      = "v0.38"
+)
+
+// eventDecoder decodes the websocket events used for monitoring for a single consensus version. The event type
+// names are the same in every version.
+type eventDecoder struct {
+	version string
+
+	block func(b []byte) (*rawBlock, error)
+	vote  func(b []byte) (*rawVote, error)
+	round func(b []byte) (*rawRound, error)
+}
+
+var eventDecoders = map[string]*eventDecoder{
+	tendermint034: {version: tendermint034, block: decodeBlock, vote: decodeVote, round: decodeRound},
+	comet037:      {version: comet037, block: decodeBlock, vote: decodeVote, round: decodeRound},
+	comet038:      {version: comet038, block: decodeBlock, vote: decodeVote, round: decodeRound},
+}
+
+// consensusVersion maps the version reported in node_info.version to the matching event encoding. Releases newer
+// than the latest known version are assumed to be compatible with it.
+func consensusVersion(nodeVersion string) (string, error) {
+	split := strings.Split(strings.TrimPrefix(strings.TrimSpace(nodeVersion), "v"), ".")
+	if len(split) < 2 {
+		return tendermint034, fmt.Errorf("could not parse version %q", nodeVersion)
+	}
+	major, err := strconv.Atoi(split[0])
+	if err != nil {
+		return tendermint034, fmt.Errorf("could not parse version %q", nodeVersion)
+	}
+	minor, err := strconv.Atoi(strings.SplitN(split[1], "-", 2)[0])
+	if err != nil {
+		return tendermint034, fmt.Errorf("could not parse version %q", nodeVersion)
+	}
+	switch {
+	case major == 0 && minor < 37:
+		return tendermint034, nil
+	case major == 0 && minor == 37:
+		return comet037, nil
+	default:
+		return comet038, nil
+	}
+}
+
+// newEventDecoder returns the decoders for a node's version, falling back to v0.34 if it can't be determined.
+func newEventDecoder(nodeVersion string) (*eventDecoder, error) {
+	v, err := consensusVersion(nodeVersion)
+	return eventDecoders[v], err
+}
+
+// decodeBlock decodes a NewBlock event for every version. Only the header and last commit are used, which are encoded
+// the same way in each version. The block results, which v0.37 changed from base64 to strings and v0.38 moved to
+// result_finalize_block, are not decoded.
+func decodeBlock(b []byte) (*rawBlock, error) {
+	block := &rawBlock{}
+	if err := json.Unmarshal(b, block); err != nil {
+		return nil, err
+	}
+	if block.Block.Header.Height.val() == 0 {
+		return nil, errors.New("block did not include a height")
+	}
+	return block, nil
+}
+
+// decodeVote decodes a Vote event for every version. Only the type, height and validator are used, these are encoded
+// the same way in each version, and the extensions added in v0.38 are not needed.
+func decodeVote(b []byte) (*rawVote, error) {
+	vote := &rawVote{}
+	if err := json.Unmarshal(b, vote); err != nil {
+		return nil, err
+	}
+	return vote, nil
+}
+
+func decodeRound(b []byte) (*rawRound, error) {
+	round := &rawRound{}
+	if err := json.Unmarshal(b, round); err != nil {
+		return nil, err
+	}
+	return round, nil
+}
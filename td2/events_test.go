@@ -0,0 +1,115 @@
+package tenderduty
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func loadReply(t *testing.T, version, name string) *WsReply {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join("testdata", "events", version, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply := &WsReply{}
+	if err = json.Unmarshal(b, reply); err != nil {
+		t.Fatal(err)
+	}
+	return reply
+}
+
+func TestEventDecoders(t *testing.T) {
+	// what each version's fixtures contain, see testdata/events/README.md. The absent validator is missing from the
+	// block's last commit.
+	for version, expect := range map[string]struct {
+		height, voteHeight, roundHeight int64
+		proposer, signer                string
+		absent                          string
+	}{
+		tendermint034: {1234, 1235, 1235, "1D8CA4776FD99712B07F581AC42F35B060496838", "1D8CA4776FD99712B07F581AC42F35B060496838", "E87739F58AEEBD94A71FE531E0716E2B8D004B2C"},
+		comet037:      {1234, 1235, 1235, "1D8CA4776FD99712B07F581AC42F35B060496838", "1D8CA4776FD99712B07F581AC42F35B060496838", "E87739F58AEEBD94A71FE531E0716E2B8D004B2C"},
+		comet038:      {1234, 1235, 1235, "1D8CA4776FD99712B07F581AC42F35B060496838", "1D8CA4776FD99712B07F581AC42F35B060496838", "E87739F58AEEBD94A71FE531E0716E2B8D004B2C"},
+	} {
+		t.Run(version, func(t *testing.T) {
+			b, err := os.ReadFile(filepath.Join("testdata", "events", version, "status.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			status := &struct {
+				Result struct {
+					NodeInfo struct {
+						Version string `json:"version"`
+					} `json:"node_info"`
+				} `json:"result"`
+			}{}
+			if err = json.Unmarshal(b, status); err != nil {
+				t.Fatal(err)
+			}
+			dec, err := newEventDecoder(status.Result.NodeInfo.Version)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if dec.version != version {
+				t.Fatalf("expected %s decoders for %s, got %s", version, status.Result.NodeInfo.Version, dec.version)
+			}
+
+			reply := loadReply(t, version, "new_block.json")
+			if reply.Type() != `tendermint/event/NewBlock` {
+				t.Errorf("unexpected block event type %s", reply.Type())
+			}
+			block, err := dec.block(reply.Value())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if block.Block.Header.Height.val() != expect.height || block.Block.Header.ProposerAddress != expect.proposer || block.Block.Header.Time.IsZero() {
+				t.Errorf("unexpected block header: %+v", block.Block.Header)
+			}
+			if !block.find(expect.signer) || block.find(expect.absent) {
+				t.Error("signatures were not decoded correctly")
+			}
+
+			reply = loadReply(t, version, "vote.json")
+			if reply.Type() != `tendermint/event/Vote` {
+				t.Errorf("unexpected vote event type %s", reply.Type())
+			}
+			vote, err := dec.vote(reply.Value())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if vote.Vote.Height.val() != expect.voteHeight || vote.Vote.ValidatorAddress != expect.signer || vote.Vote.Type.String() != "SIGNED_MSG_TYPE_PREVOTE" {
+				t.Errorf("unexpected vote: %+v", vote.Vote)
+			}
+
+			reply = loadReply(t, version, "new_round.json")
+			if reply.Type() != `tendermint/event/NewRound` {
+				t.Errorf("unexpected round event type %s", reply.Type())
+			}
+			round, err := dec.round(reply.Value())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if round.Height.val() != expect.roundHeight || round.Proposer.Address == "" {
+				t.Errorf("unexpected round: %+v", round)
+			}
+		})
+	}
+}
+
+func TestConsensusVersion(t *testing.T) {
+	for nodeVersion, expected := range map[string]string{
+		"0.34.24":             tendermint034,
+		"v0.34.27-terra.rc1":  tendermint034,
+		"0.37.0-alpha.3":      comet037,
+		"0.38.0-rc3":          comet038,
+		"1.0.0":               comet038,
+		"not-a-version":       tendermint034,
+		"0.33.9":              tendermint034,
+		"0.37.4+cosmos-patch": comet037,
+	} {
+		if v, _ := consensusVersion(nodeVersion); v != expected {
+			t.Errorf("expected %s for %s, got %s", expected, nodeVersion, v)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package tenderduty
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	rpchttp "github.com/tendermint/tendermint/rpc/client/http"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// grpcRetry is how long queries use ABCI after every gRPC endpoint has failed, before gRPC is tried again.
+const grpcRetry = 5 * time.Minute
+
+// queryFunc performs a cosmos-sdk query by its gRPC method name, ie "/cosmos.staking.v1beta1.Query/Validator", and
+// returns the serialized response. An empty response is returned as nil.
+type queryFunc func(ctx context.Context, path string, req []byte) ([]byte, error)
+
+// queryError is returned when the application rejected a query, trying another endpoint won't help.
+type queryError struct {
+	path string
+	log  string
+}
+
+func (e *queryError) Error() string {
+	return fmt.Sprintf("query %s failed: %s", e.path, e.log)
+}
+
+// abciQuery returns a queryFunc that uses the /abci_query RPC endpoint.
+func abciQuery(client *rpchttp.HTTP) queryFunc {
+	return func(ctx context.Context, path string, req []byte) ([]byte, error) {
+		resp, err := client.ABCIQuery(ctx, path, req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.Response.Code != 0 {
+			return nil, &queryError{path: path, log: resp.Response.Log}
+		}
+		if len(resp.Response.Value) == 0 {
+			return nil, nil
+		}
+		return resp.Response.Value, nil
+	}
+}
+
+// rawCodec passes serialized protobuf messages through gRPC unchanged, so requests and responses are built the same
+// way for both gRPC and ABCI queries.
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return nil, fmt.Errorf("rawCodec can't marshal %T", v)
+	}
+	return *b, nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("rawCodec can't unmarshal into %T", v)
+	}
+	*b = append((*b)[:0], data...)
+	return nil
+}
+
+func (rawCodec) Name() string {
+	return "proto"
+}
+
+// grpcPool holds the connections to a chain's gRPC endpoints. Connections are made the first time they are used,
+// and the active endpoint only changes when it fails.
+type grpcPool struct {
+	mux       sync.Mutex
+	endpoints []string
+	conns     []*grpc.ClientConn
+	active    int
+	failed    time.Time // when every endpoint last failed
+}
+
+func newGrpcPool(endpoints []string) *grpcPool {
+	return &grpcPool{
+		endpoints: endpoints,
+		conns:     make([]*grpc.ClientConn, len(endpoints)),
+	}
+}
+
+// available is false after every endpoint has failed, until grpcRetry has passed.
+func (g *grpcPool) available() bool {
+	g.mux.Lock()
+	defer g.mux.Unlock()
+	return g.failed.IsZero() || time.Since(g.failed) > grpcRetry
+}
+
+// dialGrpc connects to an endpoint, https:// uses TLS, and http:// or a bare host:port is plaintext.
+func dialGrpc(endpoint string) (*grpc.ClientConn, error) {
+	creds := insecure.NewCredentials()
+	target := endpoint
+	if strings.Contains(endpoint, "://") {
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("invalid grpc endpoint %s: %v", endpoint, err)
+		}
+		switch u.Scheme {
+		case "https", "grpcs":
+			creds = credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+			target = u.Host
+			if u.Port() == "" {
+				target += ":443"
+			}
+		case "http", "grpc", "tcp":
+			target = u.Host
+		default:
+			return nil, fmt.Errorf("grpc endpoint %s has an unknown scheme %s", endpoint, u.Scheme)
+		}
+	}
+	return grpc.Dial(target, grpc.WithTransportCredentials(creds))
+}
+
+// shouldFailover decides if an error means the endpoint is unusable, rather than the query being invalid.
+func shouldFailover(err error) bool {
+	var qe *queryError
+	if errors.As(err, &qe) {
+		return false
+	}
+	s, ok := status.FromError(err)
+	if !ok {
+		return true
+	}
+	switch s.Code() {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Unimplemented, codes.Unknown, codes.Internal, codes.Canceled:
+		return true
+	}
+	return false
+}
+
+// invoke runs a query against the active endpoint, moving to the next endpoint if it fails.
+func (g *grpcPool) invoke(ctx context.Context, path string, req []byte) ([]byte, error) {
+	g.mux.Lock()
+	start := g.active
+	g.mux.Unlock()
+	var err error
+	for i := 0; i < len(g.endpoints); i++ {
+		n := (start + i) % len(g.endpoints)
+		g.mux.Lock()
+		if g.conns[n] == nil {
+			g.conns[n], err = dialGrpc(g.endpoints[n])
+		}
+		conn := g.conns[n]
+		g.mux.Unlock()
+		if conn == nil {
+			continue
+		}
+		resp := make([]byte, 0)
+		err = conn.Invoke(ctx, path, &req, &resp, grpc.ForceCodec(rawCodec{}))
+		if err != nil && shouldFailover(err) {
+			continue
+		}
+		g.mux.Lock()
+		g.active = n
+		g.failed = time.Time{}
+		g.mux.Unlock()
+		if err != nil {
+			return nil, &queryError{path: path, log: status.Convert(err).Message()}
+		}
+		if len(resp) == 0 {
+			return nil, nil
+		}
+		return resp, nil
+	}
+	g.mux.Lock()
+	g.failed = time.Now()
+	g.mux.Unlock()
+	return nil, fmt.Errorf("all grpc endpoints failed, last error: %v", err)
+}
+
+// grpcEndpoints returns the gRPC connections for a chain, validators sharing a chain also share the primary's.
+func (cc *ChainConfig) grpcEndpoints() *grpcPool {
+	if cc.primary != nil {
+		return cc.primary.grpcEndpoints()
+	}
+	return cc.grpcConns
+}
+
+// query runs a cosmos-sdk query using gRPC if endpoints are configured, otherwise using ABCI. If the gRPC endpoints
+// are down the ABCI query is used, and vice versa.
+func (cc *ChainConfig) query(ctx context.Context, path string, req []byte) ([]byte, error) {
+	var abci queryFunc
+	if cc.client != nil {
+		abci = abciQuery(cc.client)
+	}
+	return failoverQuery(ctx, cc.ChainId, cc.grpcEndpoints(), abci, path, req)
+}
+
+// failoverQuery runs a query with the gRPC pool while it is available, using the ABCI query when it fails. Either may
+// be nil.
+func failoverQuery(ctx context.Context, chainId string, pool *grpcPool, abci queryFunc, path string, req []byte) ([]byte, error) {
+	tried := false
+	if pool != nil && pool.available() {
+		tried = true
+		resp, err := pool.invoke(ctx, path, req)
+		if err == nil || !shouldFailover(err) {
+			return resp, err
+		}
+		l(fmt.Sprintf("⚠️ %-12s using ABCI queries for %s: %v", chainId, grpcRetry, err))
+	}
+	if abci == nil {
+		if pool != nil {
+			return pool.invoke(ctx, path, req)
+		}
+		return nil, errors.New("nil rpc client")
+	}
+	resp, err := abci(ctx, path, req)
+	if err != nil && pool != nil && !tried && shouldFailover(err) {
+		// the RPC node is having problems too, try gRPC again without waiting
+		return pool.invoke(ctx, path, req)
+	}
+	return resp, err
+}
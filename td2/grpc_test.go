@@ -0,0 +1,99 @@
+package tenderduty
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// grpcServer serves every gRPC method with handle, the requests and responses are passed through as serialized
+// protobuf messages. It returns the server's address.
+func grpcServer(t *testing.T, handle func(path string, req []byte) ([]byte, error)) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer(grpc.ForceServerCodec(rawCodec{}), grpc.UnknownServiceHandler(func(_ interface{}, stream grpc.ServerStream) error {
+		path, _ := grpc.MethodFromServerStream(stream)
+		req := make([]byte, 0)
+		if err := stream.RecvMsg(&req); err != nil {
+			return err
+		}
+		resp, err := handle(path, req)
+		if err != nil {
+			return err
+		}
+		return stream.SendMsg(&resp)
+	}))
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+	t.Cleanup(srv.Stop)
+	return lis.Addr().String()
+}
+
+func TestFailoverQuery(t *testing.T) {
+	var grpcCalls, abciCalls int
+	var grpcErr, abciErr error
+	addr := grpcServer(t, func(string, []byte) ([]byte, error) {
+		grpcCalls += 1
+		return []byte("grpc"), grpcErr
+	})
+	abci := func(context.Context, string, []byte) ([]byte, error) {
+		abciCalls += 1
+		if abciErr != nil {
+			return nil, abciErr
+		}
+		return []byte("abci"), nil
+	}
+	pool := newGrpcPool([]string{addr})
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		name                 string
+		grpcErr, abciErr     error
+		expect               string
+		grpcCalls, abciCalls int
+		fails                bool
+	}{
+		{name: "grpc", expect: "grpc", grpcCalls: 1},
+		{name: "query rejected", grpcErr: status.Error(codes.NotFound, "no validator"), grpcCalls: 1, fails: true},
+		{name: "grpc down", grpcErr: status.Error(codes.Unavailable, "down"), expect: "abci", grpcCalls: 1, abciCalls: 1},
+		// every endpoint failed, ABCI is used until grpcRetry has passed
+		{name: "grpc skipped", expect: "abci", abciCalls: 1},
+		{name: "abci down", abciErr: errors.New("connection refused"), expect: "grpc", grpcCalls: 1, abciCalls: 1},
+	} {
+		grpcCalls, abciCalls = 0, 0
+		grpcErr, abciErr = tc.grpcErr, tc.abciErr
+		resp, err := failoverQuery(ctx, "test-1", pool, abci, "/cosmos.staking.v1beta1.Query/Validator", nil)
+		if (err != nil) != tc.fails || (!tc.fails && !bytes.Equal(resp, []byte(tc.expect))) {
+			t.Errorf("%s: expected %q, got %q %v", tc.name, tc.expect, resp, err)
+		}
+		if grpcCalls != tc.grpcCalls || abciCalls != tc.abciCalls {
+			t.Errorf("%s: expected %d grpc and %d abci queries, got %d and %d", tc.name, tc.grpcCalls, tc.abciCalls, grpcCalls, abciCalls)
+		}
+	}
+
+	// once grpcRetry has passed, gRPC is used again
+	pool.failed = time.Now().Add(-grpcRetry - time.Second)
+	grpcCalls, abciCalls, abciErr = 0, 0, nil
+	if resp, err := failoverQuery(ctx, "test-1", pool, abci, "/cosmos.staking.v1beta1.Query/Validator", nil); err != nil || string(resp) != "grpc" || abciCalls != 0 {
+		t.Errorf("expected gRPC to be retried, got %q %v", resp, err)
+	}
+
+	// without an rpc client only gRPC is used
+	if resp, err := failoverQuery(ctx, "test-1", pool, nil, "/cosmos.staking.v1beta1.Query/Validator", nil); err != nil || string(resp) != "grpc" {
+		t.Errorf("expected gRPC without a client, got %q %v", resp, err)
+	}
+	if _, err := failoverQuery(ctx, "test-1", nil, nil, "/cosmos.staking.v1beta1.Query/Validator", nil); err == nil {
+		t.Error("expected an error with no endpoints")
+	}
+}
@@ -0,0 +1,139 @@
+package tenderduty
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	rpchttp "github.com/tendermint/tendermint/rpc/client/http"
+	coretypes "github.com/tendermint/tendermint/rpc/core/types"
+)
+
+// HealthConfig holds optional health checks for a node, in addition to the node responding, being on the correct
+// network, and not catching up. Zero values disable a check.
+type HealthConfig struct {
+	// MinPeers is the minimum number of peers reported by /net_info
+	MinPeers int `yaml:"min_peers"`
+	// MaxMempool is the maximum number of transactions reported by /num_unconfirmed_txs
+	MaxMempool int `yaml:"max_mempool_txs"`
+	// Version is the expected tendermint version reported in node_info.version
+	Version string `yaml:"version"`
+	// AppVersion is the expected application version reported by /abci_info
+	AppVersion string `yaml:"app_version"`
+	// MaxLag is the maximum number of seconds the node's latest block time can be behind the current time
+	MaxLag int `yaml:"max_lag_seconds"`
+}
+
+// healthCheck is a single named check run against a node. It returns a non-empty reason if the node fails the check.
+type healthCheck struct {
+	name string
+	run  func(ctx context.Context, c *rpchttp.HTTP, status *coretypes.ResultStatus) (reason string)
+}
+
+// healthChecks builds the list of checks to run against a node based on its configuration.
+func (nc *NodeConfig) healthChecks() []healthCheck {
+	checks := []healthCheck{
+		{
+			name: "catching_up",
+			run: func(ctx context.Context, c *rpchttp.HTTP, status *coretypes.ResultStatus) string {
+				if status.SyncInfo.CatchingUp {
+					return "not synced"
+				}
+				return ""
+			},
+		},
+	}
+	if nc.Health.MaxLag > 0 {
+		checks = append(checks, healthCheck{
+			name: "lag",
+			run: func(ctx context.Context, c *rpchttp.HTTP, status *coretypes.ResultStatus) string {
+				if lag := time.Since(status.SyncInfo.LatestBlockTime); lag > time.Duration(nc.Health.MaxLag)*time.Second {
+					return fmt.Sprintf("lagging %.0fs behind (max %ds)", lag.Seconds(), nc.Health.MaxLag)
+				}
+				return ""
+			},
+		})
+	}
+	if nc.Health.MinPeers > 0 {
+		checks = append(checks, healthCheck{
+			name: "peers",
+			run: func(ctx context.Context, c *rpchttp.HTTP, status *coretypes.ResultStatus) string {
+				netInfo, err := c.NetInfo(ctx)
+				if err != nil {
+					return "unable to query /net_info"
+				}
+				if netInfo.NPeers < nc.Health.MinPeers {
+					return fmt.Sprintf("only connected to %d peers (min %d)", netInfo.NPeers, nc.Health.MinPeers)
+				}
+				return ""
+			},
+		})
+	}
+	if nc.Health.MaxMempool > 0 {
+		checks = append(checks, healthCheck{
+			name: "mempool",
+			run: func(ctx context.Context, c *rpchttp.HTTP, status *coretypes.ResultStatus) string {
+				mempool, err := c.NumUnconfirmedTxs(ctx)
+				if err != nil {
+					return "unable to query /num_unconfirmed_txs"
+				}
+				if mempool.Total > nc.Health.MaxMempool {
+					return fmt.Sprintf("mempool has %d txs (max %d)", mempool.Total, nc.Health.MaxMempool)
+				}
+				return ""
+			},
+		})
+	}
+	if nc.Health.Version != "" {
+		checks = append(checks, healthCheck{
+			name: "version",
+			run: func(ctx context.Context, c *rpchttp.HTTP, status *coretypes.ResultStatus) string {
+				if status.NodeInfo.Version != nc.Health.Version {
+					return fmt.Sprintf("running tendermint %s (expected %s)", status.NodeInfo.Version, nc.Health.Version)
+				}
+				return ""
+			},
+		})
+	}
+	if nc.Health.AppVersion != "" {
+		checks = append(checks, healthCheck{
+			name: "app_version",
+			run: func(ctx context.Context, c *rpchttp.HTTP, status *coretypes.ResultStatus) string {
+				// queried again rather than using nc.appVersion, which keeps its last value when /abci_info fails
+				info, err := c.ABCIInfo(ctx)
+				if err != nil {
+					return "unable to query /abci_info"
+				}
+				if strings.TrimPrefix(info.Response.Version, "v") != strings.TrimPrefix(nc.Health.AppVersion, "v") {
+					return fmt.Sprintf("running app version %s (expected %s)", info.Response.Version, nc.Health.AppVersion)
+				}
+				return ""
+			},
+		})
+	}
+	return checks
+}
+
+// runHealthChecks runs every check for a node, returning the reasons for any failures. Prometheus is updated
+// with the result of each individual check.
+func (cc *ChainConfig) runHealthChecks(ctx context.Context, node *NodeConfig, c *rpchttp.HTTP, status *coretypes.ResultStatus) []string {
+	failed := make([]string, 0)
+	for _, check := range node.healthChecks() {
+		reason := check.run(ctx, c, status)
+		if td.Prom {
+			var v float64
+			if reason != "" {
+				v = 1
+			}
+			td.statsChan <- cc.mkCheckUpdate(node.Url, check.name, v)
+		}
+		if reason != "" {
+			failed = append(failed, reason)
+		}
+	}
+	if td.Prom && !status.SyncInfo.LatestBlockTime.IsZero() {
+		td.statsChan <- cc.mkUpdate(metricNodeLagSeconds, time.Since(status.SyncInfo.LatestBlockTime).Seconds(), node.Url)
+	}
+	return failed
+}
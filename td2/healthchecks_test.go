@@ -0,0 +1,52 @@
+package tenderduty
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	rpchttp "github.com/tendermint/tendermint/rpc/client/http"
+	coretypes "github.com/tendermint/tendermint/rpc/core/types"
+)
+
+func TestAppVersionCheck(t *testing.T) {
+	up := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := struct {
+			Id json.RawMessage `json:"id"`
+		}{}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if !up {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":` + string(req.Id) + `,"result":{"response":{"version":"v1.2.0"}}}`))
+	}))
+	defer server.Close()
+	c, err := rpchttp.New(server.URL, "/websocket")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	node := &NodeConfig{Url: server.URL, Health: HealthConfig{AppVersion: "1.2.0"}}
+	check := node.healthChecks()[1]
+	if check.name != "app_version" {
+		t.Fatalf("expected the app_version check, got %s", check.name)
+	}
+	status := &coretypes.ResultStatus{}
+	if reason := check.run(context.Background(), c, status); reason != "" {
+		t.Errorf("expected the check to pass, got %q", reason)
+	}
+	node.Health.AppVersion = "v1.3.0"
+	if reason := check.run(context.Background(), c, status); reason != "running app version v1.2.0 (expected v1.3.0)" {
+		t.Errorf("expected the wrong version to fail, got %q", reason)
+	}
+
+	// a matching version from an earlier query doesn't pass the check when /abci_info is failing
+	node.Health.AppVersion, node.appVersion, up = "v1.2.0", "v1.2.0", false
+	if reason := check.run(context.Background(), c, status); reason != "unable to query /abci_info" {
+		t.Errorf("expected the failed query to fail the check, got %q", reason)
+	}
+}
@@ -17,12 +17,13 @@ func init() {
 	log.SetFlags(log.LstdFlags)
 	log.SetOutput(os.Stderr)
 	dash.Content = content
+	dash.Redact = redact
 
 	// use a channel for logging, two reasons: several logs could hit at once (formatting,) and to broadcast
 	// messages to the monitoring dashboard
 	go func() {
 		for msg := range logs {
-			msg = strings.TrimRight(strings.TrimLeft(fmt.Sprint(msg), "["), "]")
+			msg = redact(strings.TrimRight(strings.TrimLeft(fmt.Sprint(msg), "["), "]"))
 			log.Println("tenderduty | ", msg)
 			if td.EnableDash && !td.HideLogs && td.logChan != nil {
 				td.logChan <- dash.LogMessage{
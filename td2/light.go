@@ -0,0 +1,330 @@
+package tenderduty
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	tmbytes "github.com/tendermint/tendermint/libs/bytes"
+	tmjson "github.com/tendermint/tendermint/libs/json"
+	"github.com/tendermint/tendermint/light"
+	"github.com/tendermint/tendermint/light/provider"
+	lighthttp "github.com/tendermint/tendermint/light/provider/http"
+	rpchttp "github.com/tendermint/tendermint/rpc/client/http"
+	"github.com/tendermint/tendermint/types"
+)
+
+const (
+	lightTimeout    = 30 * time.Second // how long verifying a single height can take, including bisection
+	lightClockDrift = 10 * time.Second
+	lightBackwards  = 100  // how far below the highest verified height a node can be and still be checked
+	lightCacheSize  = 1000 // verified headers kept for checking nodes that are behind
+)
+
+// LightClientConfig enables light client verification of the blocks tenderduty uses. Starting from a trusted
+// header, every header is verified against the validator set that signed it, and nodes serving blocks that can't be
+// verified are treated as down.
+type LightClientConfig struct {
+	// Enabled turns on verification for the chain
+	Enabled bool `yaml:"enabled"`
+	// TrustedHeight is the height of a header known to be valid, it has to be within the trusting period
+	TrustedHeight int64 `yaml:"trusted_height"`
+	// TrustedHash is the hex encoded hash of the header at the trusted height
+	TrustedHash string `yaml:"trusted_hash"`
+	// TrustingPeriod is how long a validator set is trusted for, ie "336h". It must be shorter than the unbonding period.
+	TrustingPeriod string `yaml:"trusting_period"`
+}
+
+// unverifiableError is returned when a node served a header or commit that failed verification, as opposed to
+// errors talking to the node.
+type unverifiableError struct {
+	err error
+}
+
+func (e *unverifiableError) Error() string {
+	return e.err.Error()
+}
+
+func unverifiable(format string, a ...interface{}) error {
+	return &unverifiableError{err: fmt.Errorf(format, a...)}
+}
+
+// isUnverifiable returns true if the error means the node served invalid data.
+func isUnverifiable(err error) bool {
+	var ue *unverifiableError
+	return errors.As(err, &ue)
+}
+
+// lightVerifier tracks the highest verified light block for a chain, it is shared by all nodes for the chain. Verified
+// headers are cached, so a height is only verified once no matter how many nodes report it.
+type lightVerifier struct {
+	mux     sync.Mutex
+	chainId string
+	height  int64
+	hash    []byte
+	period  time.Duration
+	trusted *types.LightBlock       // highest verified block, nil until the trusted header has been fetched
+	headers map[int64]*types.Header // recently verified headers
+
+	providersMux sync.Mutex
+	providers    map[string]provider.Provider // by node url
+}
+
+// newLightVerifier checks the light client settings, the trusted header is fetched when the first node is verified.
+func newLightVerifier(chainId string, cfg LightClientConfig) (*lightVerifier, error) {
+	if cfg.TrustedHeight <= 0 {
+		return nil, errors.New("light_client trusted_height is not set")
+	}
+	hash, err := hex.DecodeString(strings.TrimPrefix(strings.ToLower(cfg.TrustedHash), "0x"))
+	if err != nil || len(hash) != 32 {
+		return nil, fmt.Errorf("light_client trusted_hash %q is not a valid hex encoded header hash", cfg.TrustedHash)
+	}
+	period, err := time.ParseDuration(cfg.TrustingPeriod)
+	if err != nil || period <= 0 {
+		return nil, fmt.Errorf("light_client trusting_period %q is not a valid duration", cfg.TrustingPeriod)
+	}
+	return &lightVerifier{
+		chainId:   chainId,
+		height:    cfg.TrustedHeight,
+		hash:      hash,
+		period:    period,
+		headers:   make(map[int64]*types.Header),
+		providers: make(map[string]provider.Provider),
+	}, nil
+}
+
+// lightClient returns the verifier for a chain, validators sharing a chain also share the primary's.
+func (cc *ChainConfig) lightClient() *lightVerifier {
+	if cc.primary != nil {
+		return cc.primary.lightClient()
+	}
+	return cc.light
+}
+
+// verifyNode checks that the block hash a node reported for a height matches the verified header. It is a no-op if
+// light client verification is not enabled.
+func (cc *ChainConfig) verifyNode(u string, height int64, hash []byte) error {
+	v := cc.lightClient()
+	if v == nil {
+		return nil
+	}
+	p, err := v.provider(u, cc.newClient)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), lightTimeout)
+	defer cancel()
+	verified, err := v.verify(ctx, p, height)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(verified, hash) {
+		return unverifiable("block %d has hash %X, the verified hash is %X", height, hash, verified)
+	}
+	return nil
+}
+
+// provider returns the light block provider for a node, it is created the first time the node is verified.
+func (v *lightVerifier) provider(u string, newClient func(string) (*rpchttp.HTTP, error)) (provider.Provider, error) {
+	v.providersMux.Lock()
+	defer v.providersMux.Unlock()
+	if p := v.providers[u]; p != nil {
+		return p, nil
+	}
+	client, err := newClient(u)
+	if err != nil {
+		return nil, err
+	}
+	v.providers[u] = lighthttp.NewWithClient(v.chainId, client)
+	return v.providers[u], nil
+}
+
+// verifyBlocks checks the blocks received on a websocket before passing them on, so that the read loop isn't blocked
+// while light blocks are fetched. Blocks that can't be verified are dropped, and the websocket is closed if its node
+// serves an unverifiable block.
+func (cc *ChainConfig) verifyBlocks(ctx context.Context, conn *TmConn, in, out chan *WsReply) {
+	for {
+		select {
+		case reply := <-in:
+			if err := cc.verifyBlock(conn.remote, reply.Value()); err != nil {
+				if isUnverifiable(err) {
+					cc.unverifiableNode(conn.remote, err)
+					_ = conn.Close()
+					return
+				}
+				l(fmt.Sprintf("⚠️ %-12s dropped a block from %s that could not be verified: %v", cc.ChainId, conn.remote, err))
+				continue
+			}
+			select {
+			case out <- reply:
+			default:
+				// the queue is full, this height is backfilled when the next block arrives.
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// verifyBlock checks a block received over the websocket.
+func (cc *ChainConfig) verifyBlock(u string, value []byte) error {
+	if cc.lightClient() == nil {
+		return nil
+	}
+	b := &struct {
+		Block *types.Block `json:"block"`
+	}{}
+	if err := tmjson.Unmarshal(value, b); err != nil {
+		return err
+	}
+	if b.Block == nil {
+		return unverifiable("new block event did not include a block")
+	}
+	return cc.checkBlock(u, b.Block)
+}
+
+// checkBlock verifies a block fetched from a node, its commit and transactions have to match the header, and the
+// header has to match the one verified by the light client.
+func (cc *ChainConfig) checkBlock(u string, block *types.Block) error {
+	if cc.lightClient() == nil {
+		return nil
+	}
+	if err := block.ValidateBasic(); err != nil {
+		return unverifiable("block %d is invalid: %v", block.Height, err)
+	}
+	return cc.verifyNode(u, block.Height, block.Hash())
+}
+
+// verify returns the verified header hash at a height, using the provider to fetch any light blocks needed.
+func (v *lightVerifier) verify(ctx context.Context, p provider.Provider, height int64) (tmbytes.HexBytes, error) {
+	v.mux.Lock()
+	defer v.mux.Unlock()
+	if err := v.init(ctx, p); err != nil {
+		return nil, err
+	}
+	if h := v.headers[height]; h != nil {
+		return h.Hash(), nil
+	}
+	if height < v.trusted.Height {
+		return v.verifyBackwards(ctx, p, height)
+	}
+	lb, err := fetchLightBlock(ctx, p, height)
+	if err != nil {
+		return nil, err
+	}
+	if err = v.verifyForward(ctx, p, lb); err != nil {
+		return nil, err
+	}
+	return lb.Hash(), nil
+}
+
+// init fetches the light block at the trusted height and checks it against the trusted hash.
+func (v *lightVerifier) init(ctx context.Context, p provider.Provider) error {
+	if v.trusted != nil {
+		return nil
+	}
+	lb, err := fetchLightBlock(ctx, p, v.height)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(lb.Hash(), v.hash) {
+		return unverifiable("header at trusted height %d has hash %X, expected %X", v.height, lb.Hash(), v.hash)
+	}
+	if light.HeaderExpired(lb.SignedHeader, v.period, time.Now()) {
+		return fmt.Errorf("the trusted header at height %d is older than the trusting period, update trusted_height and trusted_hash", v.height)
+	}
+	v.trusted = lb
+	v.headers[lb.Height] = lb.Header
+	l(fmt.Sprintf("🔏 %-12s light client initialized at trusted height %d", v.chainId, v.height))
+	return nil
+}
+
+// verifyForward verifies a light block above the highest verified block. If too much of the validator set has
+// changed to verify it directly, the heights in between are verified first by bisection.
+func (v *lightVerifier) verifyForward(ctx context.Context, p provider.Provider, lb *types.LightBlock) error {
+	err := light.Verify(v.trusted.SignedHeader, v.trusted.ValidatorSet, lb.SignedHeader, lb.ValidatorSet,
+		v.period, time.Now(), lightClockDrift, light.DefaultTrustLevel)
+	var cantTrust light.ErrNewValSetCantBeTrusted
+	var expired light.ErrOldHeaderExpired
+	switch {
+	case err == nil:
+	case errors.As(err, &cantTrust):
+		pivot, err := fetchLightBlock(ctx, p, (v.trusted.Height+lb.Height)/2)
+		if err != nil {
+			return err
+		}
+		if err = v.verifyForward(ctx, p, pivot); err != nil {
+			return err
+		}
+		return v.verifyForward(ctx, p, lb)
+	case errors.As(err, &expired):
+		return fmt.Errorf("the last verified header at height %d is older than the trusting period, update trusted_height and trusted_hash", v.trusted.Height)
+	default:
+		return unverifiable("block %d failed verification: %v", lb.Height, err)
+	}
+	v.trusted = lb
+	v.headers[lb.Height] = lb.Header
+	for h := range v.headers {
+		if h < lb.Height-lightCacheSize {
+			delete(v.headers, h)
+		}
+	}
+	return nil
+}
+
+// verifyBackwards verifies a header below the highest verified block by following the chain of hashes down from the
+// closest verified header above it.
+func (v *lightVerifier) verifyBackwards(ctx context.Context, p provider.Provider, height int64) (tmbytes.HexBytes, error) {
+	above := height + 1
+	for v.headers[above] == nil && above-height < lightBackwards {
+		above++
+	}
+	trusted := v.headers[above]
+	if trusted == nil {
+		return nil, fmt.Errorf("height %d is too far below the verified height %d to check", height, v.trusted.Height)
+	}
+	for h := above - 1; h >= height; h-- {
+		lb, err := fetchLightBlock(ctx, p, h)
+		if err != nil {
+			return nil, err
+		}
+		if err = light.VerifyBackwards(lb.Header, trusted); err != nil {
+			return nil, unverifiable("block %d failed verification: %v", h, err)
+		}
+		v.headers[h] = lb.Header
+		trusted = lb.Header
+	}
+	return trusted.Hash(), nil
+}
+
+// fetchLightBlock gets a light block from a provider, a light block that doesn't pass basic validation means the node
+// is serving invalid data.
+func fetchLightBlock(ctx context.Context, p provider.Provider, height int64) (*types.LightBlock, error) {
+	lb, err := p.LightBlock(ctx, height)
+	var bad provider.ErrBadLightBlock
+	if errors.As(err, &bad) {
+		return nil, unverifiable("node served an invalid light block at height %d: %v", height, bad.Reason)
+	}
+	return lb, err
+}
+
+// unverifiableNode marks the node the websocket is connected to as down, so that a different node is used when the
+// websocket reconnects.
+func (cc *ChainConfig) unverifiableNode(u string, err error) {
+	l(fmt.Sprintf("🔏 %-12s %s is serving unverifiable data, reconnecting: %v", cc.ChainId, u, err))
+	for _, node := range cc.Nodes {
+		if node.Url != u {
+			continue
+		}
+		if !node.down {
+			node.down = true
+			node.downSince = time.Now()
+		}
+		node.lastMsg = fmt.Sprintf("%-12s node %s is serving unverifiable data: %v", cc.name, u, err)
+	}
+}
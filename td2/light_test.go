@@ -0,0 +1,203 @@
+package tenderduty
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tendermint/tendermint/light/provider"
+	"github.com/tendermint/tendermint/light/provider/mock"
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestNewLightVerifier(t *testing.T) {
+	const hash = "3B8B1AA0B4F1C6D5A3E9F0B2C4D6E8F0A1B3C5D7E9F1A3B5C7D9E1F3A5B7C9D1"
+	for name, tc := range map[string]struct {
+		cfg LightClientConfig
+		ok  bool
+	}{
+		"valid":       {LightClientConfig{Enabled: true, TrustedHeight: 100, TrustedHash: hash, TrustingPeriod: "336h"}, true},
+		"0x prefix":   {LightClientConfig{Enabled: true, TrustedHeight: 100, TrustedHash: "0x" + hash, TrustingPeriod: "336h"}, true},
+		"no height":   {LightClientConfig{Enabled: true, TrustedHash: hash, TrustingPeriod: "336h"}, false},
+		"short hash":  {LightClientConfig{Enabled: true, TrustedHeight: 100, TrustedHash: hash[:40], TrustingPeriod: "336h"}, false},
+		"bad period":  {LightClientConfig{Enabled: true, TrustedHeight: 100, TrustedHash: hash, TrustingPeriod: "two weeks"}, false},
+		"zero period": {LightClientConfig{Enabled: true, TrustedHeight: 100, TrustedHash: hash, TrustingPeriod: "0s"}, false},
+	} {
+		if _, err := newLightVerifier("test-1", tc.cfg); (err == nil) != tc.ok {
+			t.Errorf("%s: unexpected result %v", name, err)
+		}
+	}
+}
+
+func TestVerifyBlockRejectsInvalidCommit(t *testing.T) {
+	v, err := newLightVerifier("test-1", LightClientConfig{
+		Enabled:        true,
+		TrustedHeight:  1,
+		TrustedHash:    "3B8B1AA0B4F1C6D5A3E9F0B2C4D6E8F0A1B3C5D7E9F1A3B5C7D9E1F3A5B7C9D1",
+		TrustingPeriod: "336h",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cc := &ChainConfig{ChainId: "test-1", light: v}
+	// the fixture's blocks are valid, so only the node can't be reached. With a tampered signature the commit no
+	// longer matches the header's last commit hash, and the block should be rejected before any node is contacted.
+	for _, version := range []string{tendermint034, comet037, comet038} {
+		value := loadReply(t, version, "new_block.json").Value()
+		if err = cc.verifyBlock("http://127.0.0.1:1", value); err == nil || isUnverifiable(err) {
+			t.Errorf("%s: expected a connection error, got %v", version, err)
+		}
+		tampered := bytes.Replace(value, []byte(`"signature": "`), []byte(`"signature": "AAAA`), 1)
+		if bytes.Equal(tampered, value) {
+			t.Fatalf("%s: no signature to tamper with", version)
+		}
+		if err = cc.verifyBlock("http://127.0.0.1:1", tampered); !isUnverifiable(err) {
+			t.Errorf("%s: expected an unverifiable block, got %v", version, err)
+		}
+	}
+}
+
+func TestVerifyBlocksDropsUnverified(t *testing.T) {
+	_, v := newVerifierChain(t)
+	cc := &ChainConfig{ChainId: "test-1", light: v}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	in, out := make(chan *WsReply), make(chan *WsReply, 1)
+	// the node can't be reached, so the block can't be verified and has to be dropped
+	go cc.verifyBlocks(ctx, &TmConn{remote: "http://127.0.0.1:1"}, in, out)
+	in <- loadReply(t, tendermint034, "new_block.json")
+	in <- loadReply(t, tendermint034, "new_block.json")
+	select {
+	case <-out:
+		t.Error("a block that could not be verified was passed on")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// countingProvider counts the light blocks fetched from a provider.
+type countingProvider struct {
+	provider.Provider
+	fetched map[int64]int
+}
+
+func (p *countingProvider) LightBlock(ctx context.Context, height int64) (*types.LightBlock, error) {
+	p.fetched[height] += 1
+	return p.Provider.LightBlock(ctx, height)
+}
+
+// newVerifierChain returns a chain of 20 heights, with the validator set replaced entirely at height 11, and a
+// verifier trusting height 1.
+func newVerifierChain(t *testing.T) (*testChain, *lightVerifier) {
+	t.Helper()
+	a, aKeys := newValSet(t, "set-a", 4)
+	b, bKeys := newValSet(t, "set-b", 4)
+	for k, v := range bKeys {
+		aKeys[k] = v
+	}
+	sets := make([]*types.ValidatorSet, 0)
+	for h := 1; h <= 20; h++ {
+		if h <= 10 {
+			sets = append(sets, a)
+		} else {
+			sets = append(sets, b)
+		}
+	}
+	chain := newTestChain(t, "test-1", sets, aKeys)
+	v, err := newLightVerifier("test-1", LightClientConfig{
+		Enabled:        true,
+		TrustedHeight:  1,
+		TrustedHash:    chain.headers[1].Hash().String(),
+		TrustingPeriod: "336h",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return chain, v
+}
+
+func TestLightVerify(t *testing.T) {
+	chain, v := newVerifierChain(t)
+	p := &countingProvider{Provider: mock.New(chain.chainId, chain.headers, chain.vals), fetched: make(map[int64]int)}
+	ctx := context.Background()
+
+	// same validator set, verified directly
+	hash, err := v.verify(ctx, p, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(hash, chain.headers[5].Hash()) {
+		t.Errorf("unexpected hash for height 5 %X", hash)
+	}
+
+	// the set changed completely at 11, the heights in between are verified by bisection
+	hash, err = v.verify(ctx, p, 15)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(hash, chain.headers[15].Hash()) {
+		t.Errorf("unexpected hash for height 15 %X", hash)
+	}
+	if v.headers[10] == nil || v.headers[11] == nil || v.trusted.Height != 15 {
+		t.Errorf("expected bisection to verify 10 and 11, trusted height is %d", v.trusted.Height)
+	}
+
+	// below the highest verified height, the hashes are followed backwards
+	hash, err = v.verify(ctx, p, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(hash, chain.headers[3].Hash()) {
+		t.Errorf("unexpected hash for height 3 %X", hash)
+	}
+
+	// verified heights are cached, nothing is fetched again
+	before := len(p.fetched)
+	for _, h := range []int64{3, 5, 15} {
+		if _, err = v.verify(ctx, p, h); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for h, n := range p.fetched {
+		if n > 1 {
+			t.Errorf("height %d was fetched %d times", h, n)
+		}
+	}
+	if len(p.fetched) != before {
+		t.Error("cached heights were fetched again")
+	}
+}
+
+func TestLightVerifyRejectsForgedHeader(t *testing.T) {
+	chain, v := newVerifierChain(t)
+	// a header at 6 with a different app hash, the commit is for the real one.
+	forged := *chain.headers[6].Header
+	forged.AppHash = []byte("forged")
+	chain.headers[6] = &types.SignedHeader{Header: &forged, Commit: chain.headers[6].Commit}
+	_, err := v.verify(context.Background(), mock.New(chain.chainId, chain.headers, chain.vals), 6)
+	if !isUnverifiable(err) {
+		t.Errorf("expected the forged header to be unverifiable, got %v", err)
+	}
+
+	// a node whose trusted header doesn't match the configured hash
+	_, v = newVerifierChain(t)
+	other, keys := newValSet(t, "set-c", 4)
+	fork := newTestChain(t, "test-1", []*types.ValidatorSet{other, other}, keys)
+	if _, err = v.verify(context.Background(), mock.New(fork.chainId, fork.headers, fork.vals), 2); !isUnverifiable(err) {
+		t.Errorf("expected a different trusted header to be unverifiable, got %v", err)
+	}
+}
+
+func TestLightProviderCache(t *testing.T) {
+	_, v := newVerifierChain(t)
+	cc := &ChainConfig{ChainId: "test-1"}
+	a, err := v.provider("http://127.0.0.1:1", cc.newClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := v.provider("http://127.0.0.1:1", cc.newClient)
+	c, _ := v.provider("http://127.0.0.1:2", cc.newClient)
+	if a != b || a == c {
+		t.Error("expected one provider per node")
+	}
+}
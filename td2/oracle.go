@@ -0,0 +1,237 @@
+package tenderduty
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// oracleModule is a supported oracle module's gRPC query service, and where its Params has the vote period and slash
+// window. The x/oracle modules on these chains all descend from Terra's, and share the same request and response
+// layout for the MissCounter query, but some have dropped fields from Params.
+type oracleModule struct {
+	prefix      string
+	votePeriod  protowire.Number
+	slashWindow protowire.Number
+}
+
+var oracleModules = map[string]oracleModule{
+	"umee": {prefix: "/umee.oracle.v1.Query/", votePeriod: 1, slashWindow: 7},
+	"ojo":  {prefix: "/ojo.oracle.v1.Query/", votePeriod: 1, slashWindow: 7},
+	// kujira removed reward_distribution_window (4) from Params, the fields after it moved up by one.
+	"kujira":        {prefix: "/kujira.oracle.Query/", votePeriod: 1, slashWindow: 6},
+	"terra-classic": {prefix: "/terra.oracle.v1beta1.Query/", votePeriod: 1, slashWindow: 7},
+}
+
+// OracleConfig enables monitoring of price-feeder votes on chains with an oracle module. Missing oracle votes is
+// slashed separately from missing blocks.
+type OracleConfig struct {
+	// Enabled turns on the oracle monitor for this chain
+	Enabled bool `yaml:"enabled"`
+	// Module is the oracle module in use: umee, ojo, kujira, or terra-classic.
+	Module string `yaml:"module"`
+	// QueryPrefix overrides the query service used for the module, ie "/umee.oracle.v1.Query/"
+	QueryPrefix string `yaml:"query_prefix"`
+	// VotePeriod is the number of blocks in a vote period, if 0 it is read from the module's params.
+	VotePeriod int64 `yaml:"vote_period"`
+	// SlashWindow is the number of blocks in the oracle slashing window, if 0 it is read from the module's params.
+	SlashWindow int64 `yaml:"slash_window"`
+
+	// ConsecutiveMissed is how many vote periods in a row can be missed before alerting
+	ConsecutiveMissed int `yaml:"consecutive_missed"`
+	// ConsecutivePriority is a tag for pagerduty to route on priority
+	ConsecutivePriority string `yaml:"consecutive_priority"`
+	// ConsecutiveAlerts is whether to alert on consecutive missed votes
+	ConsecutiveAlerts bool `yaml:"consecutive_enabled"`
+
+	// Window is the percentage of vote periods in the slash window missed that will trigger an alert
+	Window int `yaml:"percentage_missed"`
+	// PercentagePriority is a tag for pagerduty to route on priority
+	PercentagePriority string `yaml:"percentage_priority"`
+	// PercentageAlerts is whether to alert on percentage based misses
+	PercentageAlerts bool `yaml:"percentage_enabled"`
+}
+
+// oracleState is the most recently observed oracle voting state for a validator.
+type oracleState struct {
+	missCounter uint64
+	consecutive int
+	lastPeriod  int64
+	periods     int64 // vote periods in the slash window
+}
+
+// pct returns the percentage of the slash window's vote periods that have been missed.
+func (o *oracleState) pct() float64 {
+	if o == nil || o.periods == 0 {
+		return 0
+	}
+	return 100 * float64(o.missCounter) / float64(o.periods)
+}
+
+func (oc OracleConfig) prefix() string {
+	if oc.QueryPrefix != "" {
+		return "/" + strings.Trim(oc.QueryPrefix, "/") + "/"
+	}
+	return oracleModules[oc.Module].prefix
+}
+
+// queryOracle performs an ABCI query against the oracle module, the request is a single string in field one,
+// which is the case for both the MissCounter and Params (empty) requests.
+func (cc *ChainConfig) queryOracle(ctx context.Context, method string, arg string) ([]byte, error) {
+	var req []byte
+	if arg != "" {
+		req = protowire.AppendTag(req, 1, protowire.BytesType)
+		req = protowire.AppendString(req, arg)
+	}
+	return cc.query(ctx, cc.Oracle.prefix()+method, req)
+}
+
+// findVarint returns the value of a varint field from a serialized protobuf message, descending into the
+// message fields listed in path first. Missing fields return 0, which matches protobuf's default.
+func findVarint(b []byte, field protowire.Number, path ...protowire.Number) (uint64, error) {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return 0, protowire.ParseError(n)
+		}
+		b = b[n:]
+		switch {
+		case len(path) > 0 && num == path[0] && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return 0, protowire.ParseError(n)
+			}
+			return findVarint(v, field, path[1:]...)
+		case len(path) == 0 && num == field && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return 0, protowire.ParseError(n)
+			}
+			return v, nil
+		}
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			return 0, protowire.ParseError(n)
+		}
+		b = b[n:]
+	}
+	return 0, nil
+}
+
+// getOracleParams fills in the vote period and slash window if they were not configured, using the module's Params
+// layout. For other modules both have to be configured.
+func (cc *ChainConfig) getOracleParams(ctx context.Context) error {
+	// some modules don't have a slash window, only try to look it up once.
+	if cc.Oracle.VotePeriod > 0 && (cc.Oracle.SlashWindow > 0 || cc.oracle != nil) {
+		return nil
+	}
+	module, ok := oracleModules[cc.Oracle.Module]
+	if !ok {
+		return fmt.Errorf("the params layout of oracle module %q is not known, please set vote_period and slash_window in the config", cc.Oracle.Module)
+	}
+	b, err := cc.queryOracle(ctx, "Params", "")
+	if err != nil {
+		return err
+	}
+	if cc.Oracle.VotePeriod == 0 {
+		v, err := findVarint(b, module.votePeriod, 1)
+		if err != nil {
+			return err
+		}
+		cc.Oracle.VotePeriod = int64(v)
+	}
+	if cc.Oracle.SlashWindow == 0 {
+		v, err := findVarint(b, module.slashWindow, 1)
+		if err != nil {
+			return err
+		}
+		cc.Oracle.SlashWindow = int64(v)
+	}
+	if cc.Oracle.VotePeriod == 0 {
+		return errors.New("could not determine the oracle vote period, please set it in the config")
+	}
+	l(fmt.Sprintf("⚙️ %-12s oracle vote period is %d blocks, slash window is %d blocks", cc.ChainId, cc.Oracle.VotePeriod, cc.Oracle.SlashWindow))
+	return nil
+}
+
+// checkOracle queries the miss counter once per vote period. An increase in the counter since the last vote period
+// means the vote for that period was missed. The counter is reset at the end of each slash window.
+func (cc *ChainConfig) checkOracle(ctx context.Context) error {
+	if cc.client == nil || cc.lastBlockNum == 0 {
+		return nil
+	}
+	err := cc.getOracleParams(ctx)
+	if err != nil {
+		return err
+	}
+	period := cc.lastBlockNum / cc.Oracle.VotePeriod
+	if cc.oracle != nil && period == cc.oracle.lastPeriod {
+		return nil
+	}
+
+	b, err := cc.queryOracle(ctx, "MissCounter", cc.ValAddress)
+	if err != nil {
+		return err
+	}
+	missed, err := findVarint(b, 1)
+	if err != nil {
+		return err
+	}
+
+	var periods int64
+	if cc.Oracle.SlashWindow > 0 {
+		periods = cc.Oracle.SlashWindow / cc.Oracle.VotePeriod
+	}
+	state := cc.oracle.next(missed, period, periods)
+	if cc.oracle != nil && state.consecutive > cc.oracle.consecutive {
+		l(fmt.Sprintf("❌ warning      %s missed an oracle vote on %s (%d missed this window)", cc.valInfo.Moniker, cc.ChainId, missed))
+	}
+	cc.oracle = state
+	if td.Prom {
+		td.statsChan <- cc.mkUpdate(metricOracleMissed, float64(state.missCounter), "")
+		td.statsChan <- cc.mkUpdate(metricOracleConsecutive, float64(state.consecutive), "")
+	}
+	return nil
+}
+
+// next returns the state after a vote period with the given miss counter. Votes missed since the last period are
+// added to the consecutive count, and a period without a miss resets it.
+func (o *oracleState) next(missed uint64, period, periods int64) *oracleState {
+	state := &oracleState{missCounter: missed, lastPeriod: period, periods: periods}
+	if o == nil {
+		return state
+	}
+	switch {
+	case missed > o.missCounter:
+		state.consecutive = o.consecutive + int(missed-o.missCounter)
+	case missed < o.missCounter && missed > 0:
+		// a new slash window started, any misses are since the reset
+		state.consecutive = o.consecutive + int(missed)
+	}
+	return state
+}
+
+// monitorOracle polls the oracle module's miss counter until the context is cancelled.
+func (cc *ChainConfig) monitorOracle(ctx context.Context) {
+	tick := time.NewTicker(5 * time.Second)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+			cwt, cancel := context.WithTimeout(ctx, 10*time.Second)
+			err := cc.checkOracle(cwt)
+			cancel()
+			if err != nil {
+				l("❓ checking oracle votes for", cc.ValAddress, err)
+				// don't spam the logs if the query is broken
+				time.Sleep(time.Minute)
+			}
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package tenderduty
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestFindVarint(t *testing.T) {
+	// QueryParamsResponse{Params{vote_period: 5, vote_threshold: "0.5", slash_window: 100800}}
+	var params []byte
+	params = protowire.AppendTag(params, 1, protowire.VarintType)
+	params = protowire.AppendVarint(params, 5)
+	params = protowire.AppendTag(params, 2, protowire.BytesType)
+	params = protowire.AppendString(params, "500000000000000000")
+	params = protowire.AppendTag(params, 7, protowire.VarintType)
+	params = protowire.AppendVarint(params, 100800)
+	var resp []byte
+	resp = protowire.AppendTag(resp, 1, protowire.BytesType)
+	resp = protowire.AppendBytes(resp, params)
+
+	if v, err := findVarint(resp, 1, 1); err != nil || v != 5 {
+		t.Errorf("expected vote period of 5, got %d %v", v, err)
+	}
+	if v, err := findVarint(resp, 7, 1); err != nil || v != 100800 {
+		t.Errorf("expected slash window of 100800, got %d %v", v, err)
+	}
+	if v, err := findVarint(resp, 8, 1); err != nil || v != 0 {
+		t.Errorf("expected missing field to be 0, got %d %v", v, err)
+	}
+	if _, err := findVarint(resp[:len(resp)-1], 7, 1); err == nil {
+		t.Error("expected an error for a truncated message")
+	}
+
+	// QueryMissCounterResponse{miss_counter: 42}
+	var miss []byte
+	miss = protowire.AppendTag(miss, 1, protowire.VarintType)
+	miss = protowire.AppendVarint(miss, 42)
+	if v, err := findVarint(miss, 1); err != nil || v != 42 {
+		t.Errorf("expected miss counter of 42, got %d %v", v, err)
+	}
+}
+
+func TestOracleStateNext(t *testing.T) {
+	var state *oracleState
+	for i, tc := range []struct {
+		name        string
+		missed      uint64
+		consecutive int
+	}{
+		{"first period", 4, 0},
+		{"voted", 4, 0},
+		{"missed one", 5, 1},
+		{"missed two more", 7, 3},
+		{"voted again", 7, 0},
+		{"missed", 8, 1},
+		{"new window, voted", 0, 0},
+		{"missed", 1, 1},
+		{"another new window", 0, 0},
+	} {
+		period := int64(100 + i)
+		state = state.next(tc.missed, period, 1000)
+		if state.consecutive != tc.consecutive || state.missCounter != tc.missed || state.lastPeriod != period || state.periods != 1000 {
+			t.Errorf("%s: expected %d consecutive, got %+v", tc.name, tc.consecutive, *state)
+		}
+	}
+
+	// the counter was reset by a new slash window, but the vote was missed again since
+	state = (&oracleState{missCounter: 30, consecutive: 2}).next(1, 1, 1000)
+	if state.consecutive != 3 {
+		t.Errorf("expected misses after a reset to be counted, got %d", state.consecutive)
+	}
+}
+
+func TestGetOracleParams(t *testing.T) {
+	// Params with the vote period in field 1 and the slash window in the given field, the other of fields 6 and 7
+	// is set so that using the wrong layout gives the wrong value rather than none.
+	params := func(slashWindow protowire.Number) []byte {
+		var p []byte
+		p = protowire.AppendTag(p, 1, protowire.VarintType)
+		p = protowire.AppendVarint(p, 5)
+		for _, field := range []protowire.Number{6, 7} {
+			p = protowire.AppendTag(p, field, protowire.VarintType)
+			if field == slashWindow {
+				p = protowire.AppendVarint(p, 100800)
+			} else {
+				p = protowire.AppendVarint(p, 1)
+			}
+		}
+		return protowire.AppendBytes(protowire.AppendTag(nil, 1, protowire.BytesType), p)
+	}
+	for module, tc := range map[string]struct {
+		slashWindowField protowire.Number
+		config           OracleConfig
+		fails            bool
+	}{
+		"umee":          {slashWindowField: 7},
+		"kujira":        {slashWindowField: 6},
+		"terra-classic": {slashWindowField: 7},
+		"custom":        {slashWindowField: 7, fails: true},
+		"configured":    {config: OracleConfig{VotePeriod: 5, SlashWindow: 100800}},
+	} {
+		resp := params(tc.slashWindowField)
+		addr := grpcServer(t, func(path string, _ []byte) ([]byte, error) {
+			if path != "/test.oracle.Query/Params" {
+				t.Errorf("%s: unexpected query %s", module, path)
+			}
+			return resp, nil
+		})
+		cc := &ChainConfig{ChainId: "test-1", Oracle: tc.config, grpcConns: newGrpcPool([]string{addr})}
+		cc.Oracle.Module = module
+		cc.Oracle.QueryPrefix = "/test.oracle.Query/"
+		err := cc.getOracleParams(context.Background())
+		if tc.fails {
+			if err == nil {
+				t.Errorf("%s: expected an unknown params layout to fail", module)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(module, err)
+		}
+		if cc.Oracle.VotePeriod != 5 || cc.Oracle.SlashWindow != 100800 {
+			t.Errorf("%s: unexpected vote period %d and slash window %d", module, cc.Oracle.VotePeriod, cc.Oracle.SlashWindow)
+		}
+	}
+}
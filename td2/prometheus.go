@@ -21,6 +21,7 @@ type metricType uint8
 const (
 	metricSigned metricType = iota
 	metricProposed
+	metricProposalMissed
 	metricMissed
 	metricPrevote
 	metricPrecommit
@@ -29,11 +30,18 @@ const (
 	metricWindowSize
 	metricLastBlockSeconds
 	metricLastBlockSecondsNotFinal
+	metricAverageBlockTime
+	metricOracleMissed
+	metricOracleConsecutive
+	metricAccountBalance
+	metricBlockInterval
 
 	metricTotalNodes
 	metricUnealthyNodes
 	metricNodeLagSeconds
 	metricNodeDownSeconds
+	metricNodeCheckFailed
+	metricNodeScore
 )
 
 type promUpdate struct {
@@ -43,10 +51,16 @@ type promUpdate struct {
 	chainId  string
 	moniker  string
 	endpoint string
+	address  string
+	denom    string
+	check    string
 }
 
 type metrics map[metricType]*prometheus.GaugeVec
 
+// histograms are kept separately from the gauges since they are observed instead of set.
+type histograms map[metricType]*prometheus.HistogramVec
+
 func (m metrics) setStat(update *promUpdate) {
 	lbls := map[string]string{
 		"name":     update.name,
@@ -55,16 +69,36 @@ func (m metrics) setStat(update *promUpdate) {
 	}
 	promMux.RLock()
 	defer promMux.RUnlock()
-	if update.metric == metricNodeLagSeconds || update.metric == metricNodeDownSeconds {
+	if update.metric == metricNodeLagSeconds || update.metric == metricNodeDownSeconds || update.metric == metricNodeScore {
+		lbls["endpoint"] = update.endpoint
+	}
+	if update.metric == metricNodeCheckFailed {
 		lbls["endpoint"] = update.endpoint
+		lbls["check"] = update.check
+	}
+	if update.metric == metricAccountBalance {
+		lbls["address"] = update.address
+		lbls["denom"] = update.denom
 	}
 	m[update.metric].With(lbls).Set(update.counter)
 }
 
+func (h histograms) observe(update *promUpdate) {
+	promMux.RLock()
+	defer promMux.RUnlock()
+	h[update.metric].With(map[string]string{
+		"name":     update.name,
+		"chain_id": update.chainId,
+		"moniker":  update.moniker,
+	}).Observe(update.counter)
+}
+
 func prometheusExporter(ctx context.Context, updates chan *promUpdate) {
 	// attributes used to uniquely identify each chain
 	var chainLabels = []string{"name", "chain_id", "moniker"}
 	var hostLabels = []string{"name", "chain_id", "moniker", "endpoint"}
+	var checkLabels = []string{"name", "chain_id", "moniker", "endpoint", "check"}
+	var accountLabels = []string{"name", "chain_id", "moniker", "address", "denom"}
 
 	// setup our signing gauges
 	signed := promauto.NewGaugeVec(prometheus.GaugeOpts{
@@ -75,6 +109,10 @@ func prometheusExporter(ctx context.Context, updates chan *promUpdate) {
 		Name: "tenderduty_proposed_blocks",
 		Help: "count of blocks proposed since tenderduty was started",
 	}, chainLabels)
+	proposalMissed := promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "tenderduty_missed_proposals",
+		Help: "count of rounds where the validator was the proposer, but the block was proposed by another validator since tenderduty was started",
+	}, chainLabels)
 	missed := promauto.NewGaugeVec(prometheus.GaugeOpts{
 		Name: "tenderduty_missed_blocks",
 		Help: "count of blocks missed without seeing a precommit or prevote since tenderduty was started",
@@ -107,6 +145,27 @@ func prometheusExporter(ctx context.Context, updates chan *promUpdate) {
 		Name: "tenderduty_time_since_last_block_unfinalized",
 		Help: "how many seconds since the previous block was finalized, set regardless of finalization, useful for stall detection, not helpful for figuring average time",
 	}, chainLabels)
+	averageBlockTime := promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "tenderduty_average_block_time_seconds",
+		Help: "rolling average of the interval between the last 20 block header times",
+	}, chainLabels)
+	blockInterval := promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "tenderduty_block_interval_seconds",
+		Help:    "histogram of the interval between block header times",
+		Buckets: prometheus.ExponentialBuckets(0.5, 1.5, 12),
+	}, chainLabels)
+	oracleMissed := promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "tenderduty_oracle_missed_votes_for_window",
+		Help: "the oracle module's miss counter for the current slash window",
+	}, chainLabels)
+	oracleConsecutive := promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "tenderduty_oracle_consecutive_missed_votes",
+		Help: "the current count of consecutively missed oracle vote periods",
+	}, chainLabels)
+	accountBalance := promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "tenderduty_account_balance",
+		Help: "the balance of a monitored operator account, in the base denom",
+	}, accountLabels)
 
 	// setup node health gauges:
 	nodesMonitored := promauto.NewGaugeVec(prometheus.GaugeOpts{
@@ -127,10 +186,19 @@ func prometheusExporter(ctx context.Context, updates chan *promUpdate) {
 		Name: "tenderduty_endpoint_down_seconds",
 		Help: "how many seconds a node has been marked as unhealthy",
 	}, hostLabels)
+	nodeCheckFailed := promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "tenderduty_endpoint_health_check_failed",
+		Help: "set to 1 if a node health check is failing, the check label has the name of the check",
+	}, checkLabels)
+	nodeScore := promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "tenderduty_endpoint_score",
+		Help: "a node's health score from 0 to 100 based on latency, height lag, errors, and sync state, the best node is used for monitoring",
+	}, hostLabels)
 
 	m := metrics{
 		metricSigned:                   signed,
 		metricProposed:                 proposed,
+		metricProposalMissed:           proposalMissed,
 		metricMissed:                   missed,
 		metricPrevote:                  missedPrevote,
 		metricPrecommit:                missedPrecommit,
@@ -139,16 +207,30 @@ func prometheusExporter(ctx context.Context, updates chan *promUpdate) {
 		metricWindowSize:               windowSize,
 		metricLastBlockSeconds:         lastBlockSec,
 		metricLastBlockSecondsNotFinal: lastBlockSecUnfinalized,
+		metricAverageBlockTime:         averageBlockTime,
+		metricOracleMissed:             oracleMissed,
+		metricOracleConsecutive:        oracleConsecutive,
+		metricAccountBalance:           accountBalance,
 		metricTotalNodes:               nodesMonitored,
 		metricUnealthyNodes:            nodesUnhealthy,
-		metricNodeLagSeconds:           nodeLagSec,  // todo
-		metricNodeDownSeconds:          nodeDownSec, // todo
+		metricNodeLagSeconds:           nodeLagSec,
+		metricNodeDownSeconds:          nodeDownSec,
+		metricNodeCheckFailed:          nodeCheckFailed,
+		metricNodeScore:                nodeScore,
+	}
+
+	h := histograms{
+		metricBlockInterval: blockInterval,
 	}
 
 	go func() {
 		for {
 			select {
 			case u := <-updates:
+				if h[u.metric] != nil {
+					h.observe(u)
+					continue
+				}
 				m.setStat(u)
 			case <-ctx.Done():
 				return
@@ -0,0 +1,74 @@
+package tenderduty
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestMissedProposals(t *testing.T) {
+	const ours, other = "1D8C", "E877"
+	cc := &ChainConfig{
+		ChainId:       "test-1",
+		valInfo:       &ValInfo{Moniker: "test", Conspub: []byte{0x1d, 0x8c}, Bonded: true},
+		lastBlockNum:  99,
+		blocksResults: make([]int, showBLocks),
+		historical:    make([]bool, showBLocks),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	chans := cc.startHandlers(ctx, cancel, eventDecoders[tendermint034])
+
+	round := func(height int64, r int32, proposer string) {
+		reply := &WsReply{}
+		reply.Result.Data.Type = `tendermint/event/NewRound`
+		reply.Result.Data.Value = json.RawMessage(fmt.Sprintf(`{"height":"%d","round":%d,"proposer":{"address":%q}}`, height, r, proposer))
+		chans.rounds <- reply
+	}
+	block := func(height int64, proposer string) {
+		b := &rawBlock{}
+		if err := json.Unmarshal([]byte(fmt.Sprintf(`{"block":{"header":{"height":"%d","proposer_address":%q},"last_commit":{"signatures":[{"validator_address":%q}]}}}`, height, proposer, ours)), b); err != nil {
+			t.Fatal(err)
+		}
+		chans.blocks <- b
+	}
+	// the handlers are unbuffered, a round or block is only taken once the previous one was passed on. The second
+	// block can only be taken after the results for the first were received, so everything before it was processed.
+	sync := func() {
+		round(0, 0, other)
+		block(0, other)
+		block(0, other)
+	}
+
+	// our turn in round 1 of 100, but another validator's block was committed
+	round(100, 0, other)
+	round(100, 1, ours)
+	round(100, 2, other)
+	block(100, other)
+	sync()
+	if cc.proposalMissed != 100 || cc.statProposalMiss != 1 {
+		t.Errorf("expected a missed proposal at 100, got %d (%.0f missed)", cc.proposalMissed, cc.statProposalMiss)
+	}
+
+	// our turn at 101 and we proposed it
+	round(101, 0, ours)
+	round(101, 1, other)
+	block(101, ours)
+	sync()
+	if cc.proposalMissed != 100 || cc.statProposalMiss != 1 {
+		t.Errorf("expected only the miss at 100, got %d (%.0f missed)", cc.proposalMissed, cc.statProposalMiss)
+	}
+
+	// not our turn, and a proposer for an old height is ignored
+	round(99, 0, ours)
+	round(102, 0, other)
+	block(102, other)
+	sync()
+	if cc.statProposalMiss != 1 {
+		t.Errorf("expected no new misses, got %.0f", cc.statProposalMiss)
+	}
+	if cc.lastBlockNum != 102 || cc.blocksResults[0] != int(StatusSigned) || cc.blocksResults[1] != int(StatusProposed) {
+		t.Errorf("unexpected results at %d: %v", cc.lastBlockNum, cc.blocksResults[:4])
+	}
+}
@@ -0,0 +1,217 @@
+package tenderduty
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+// chainRegistryJson is the chain.json for a chain in the chain registry, by the registry path in cosmosPaths.
+const chainRegistryJson = "https://raw.githubusercontent.com/cosmos/chain-registry/master/%s/chain.json"
+
+const (
+	publicProbeTimeout    = 5 * time.Second  // per endpoint, when ranking the public endpoints
+	publicProbeInterval   = time.Hour        // how long a ranking is used before probing the endpoints again
+	publicRefreshInterval = 12 * time.Hour   // how often the list of endpoints is fetched from the registry
+	publicRetryInterval   = 10 * time.Minute // how long to wait before trying the registry again after it failed
+	publicMaxTries        = 3                // most public endpoints tried each time no node is available
+)
+
+// publicEndpoint is an RPC server from the chain registry's apis.rpc list.
+type publicEndpoint struct {
+	Address  string `json:"address"`
+	Provider string `json:"provider"`
+}
+
+// publicCache is saved to disk so the endpoints are known when the registry can't be reached.
+type publicCache struct {
+	ChainId   string           `json:"chain_id"`
+	Updated   time.Time        `json:"updated"`
+	Endpoints []publicEndpoint `json:"endpoints"`
+}
+
+// publicPool holds the public endpoints for a chain, and the working ones ranked by latency.
+type publicPool struct {
+	mux       sync.Mutex
+	endpoints []publicEndpoint
+	fetched   time.Time
+	failed    time.Time // when fetching from the registry last failed
+	ranked    []string  // usable endpoints, fastest first. Endpoints are removed when they fail.
+	probed    time.Time
+}
+
+// fetchChainRegistry gets the public RPC endpoints for a chain from the chain registry.
+func fetchChainRegistry(chainId string) ([]publicEndpoint, error) {
+	path, ok := getRegistryPath(chainId)
+	if !ok {
+		return nil, fmt.Errorf("%s is not in the chain registry", chainId)
+	}
+	resp, err := proxyClient().Get(fmt.Sprintf(chainRegistryJson, path))
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("chain registry returned %s", resp.Status)
+	}
+	info := &struct {
+		ChainId string `json:"chain_id"`
+		Apis    struct {
+			Rpc []publicEndpoint `json:"rpc"`
+		} `json:"apis"`
+	}{}
+	if err = json.Unmarshal(body, info); err != nil {
+		return nil, err
+	}
+	if info.ChainId != chainId {
+		return nil, fmt.Errorf("chain registry has chain id %s for %s, expected %s", info.ChainId, path, chainId)
+	}
+	if len(info.Apis.Rpc) == 0 {
+		return nil, errors.New("chain registry has no rpc endpoints")
+	}
+	return info.Apis.Rpc, nil
+}
+
+// unsafeFileRex matches characters that shouldn't be used in a file name.
+var unsafeFileRex = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// publicCacheFile is where the endpoints for a chain are cached, next to the state file.
+func publicCacheFile(chainId string) string {
+	dir := "."
+	if td != nil && td.cacheDir != "" {
+		dir = td.cacheDir
+	}
+	return filepath.Join(dir, ".tenderduty-public-"+unsafeFileRex.ReplaceAllString(chainId, "_")+".json")
+}
+
+func savePublicCache(file string, cache *publicCache) error {
+	b, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, b, 0600)
+}
+
+func loadPublicCache(file string) (*publicCache, error) {
+	//#nosec -- file name is derived from the state file's location
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	cache := &publicCache{}
+	if err = json.Unmarshal(b, cache); err != nil {
+		return nil, err
+	}
+	return cache, nil
+}
+
+// refresh updates the endpoints from the chain registry, falling back to the cache on disk if the registry can't be
+// reached. Must be called with the lock held.
+func (p *publicPool) refresh(chainId string) {
+	if time.Since(p.fetched) < publicRefreshInterval || time.Since(p.failed) < publicRetryInterval {
+		return
+	}
+	file := publicCacheFile(chainId)
+	endpoints, err := fetchChainRegistry(chainId)
+	if err == nil {
+		p.endpoints, p.fetched, p.probed = endpoints, time.Now(), time.Time{}
+		if e := savePublicCache(file, &publicCache{ChainId: chainId, Updated: p.fetched, Endpoints: endpoints}); e != nil {
+			l(fmt.Sprintf("⛑ %-12s could not cache public endpoints: %v", chainId, e))
+		}
+		return
+	}
+	p.failed = time.Now()
+	l(fmt.Sprintf("⛑ %-12s could not fetch public endpoints from the chain registry: %v", chainId, err))
+	if len(p.endpoints) > 0 {
+		return
+	}
+	cache, e := loadPublicCache(file)
+	if e != nil || cache.ChainId != chainId {
+		return
+	}
+	l(fmt.Sprintf("⛑ %-12s using %d cached public endpoints from %s", chainId, len(cache.Endpoints), cache.Updated.Format(time.RFC3339)))
+	// try the registry again at the next refresh, the cached list is only a fallback.
+	p.endpoints, p.fetched, p.probed = cache.Endpoints, time.Now(), time.Time{}
+}
+
+// probePublic checks each endpoint's chain id and sync state, returning the usable ones fastest first.
+func (cc *ChainConfig) probePublic(endpoints []publicEndpoint) []string {
+	latency := make(map[string]time.Duration)
+	mux := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for _, endpoint := range endpoints {
+		wg.Add(1)
+		go func(u string) {
+			defer wg.Done()
+			client, err := cc.newClient(u)
+			if err != nil {
+				return
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), publicProbeTimeout)
+			defer cancel()
+			start := time.Now()
+			status, err := client.Status(ctx)
+			if err != nil || status.NodeInfo.Network != cc.ChainId || status.SyncInfo.CatchingUp {
+				return
+			}
+			mux.Lock()
+			latency[u] = time.Since(start)
+			mux.Unlock()
+		}(endpoint.Address)
+	}
+	wg.Wait()
+	ranked := make([]string, 0, len(latency))
+	for u := range latency {
+		ranked = append(ranked, u)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		return latency[ranked[i]] < latency[ranked[j]]
+	})
+	return ranked
+}
+
+// publicEndpoints returns the best public endpoints to try, probing them if the ranking is empty or old.
+func (cc *ChainConfig) publicEndpoints() []string {
+	p := cc.public
+	if p == nil {
+		return nil
+	}
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.refresh(cc.ChainId)
+	if len(p.ranked) == 0 || time.Since(p.probed) > publicProbeInterval {
+		p.ranked, p.probed = cc.probePublic(p.endpoints), time.Now()
+		l(fmt.Sprintf("⛑ %-12s %d of %d public endpoints are usable", cc.ChainId, len(p.ranked), len(p.endpoints)))
+	}
+	if len(p.ranked) > publicMaxTries {
+		return append([]string{}, p.ranked[:publicMaxTries]...)
+	}
+	return append([]string{}, p.ranked...)
+}
+
+// publicFailed removes an endpoint from the ranking, so the next one is used. It is probed again with the others.
+func (cc *ChainConfig) publicFailed(u string) {
+	if cc.public == nil {
+		return
+	}
+	cc.public.mux.Lock()
+	defer cc.public.mux.Unlock()
+	for i := range cc.public.ranked {
+		if cc.public.ranked[i] == u {
+			cc.public.ranked = append(cc.public.ranked[:i], cc.public.ranked[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package tenderduty
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// statusServer is an RPC server that only answers /status.
+func statusServer(network string, catchingUp bool, delay time.Duration) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := struct {
+			ID json.RawMessage `json:"id"`
+		}{}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		time.Sleep(delay)
+		_, _ = fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":{"node_info":{"network":%q},"sync_info":{"latest_block_height":"100","catching_up":%t}}}`,
+			req.ID, network, catchingUp)
+	}))
+}
+
+func TestProbePublic(t *testing.T) {
+	slow := statusServer("test-1", false, 100*time.Millisecond)
+	defer slow.Close()
+	fast := statusServer("test-1", false, 0)
+	defer fast.Close()
+	syncing := statusServer("test-1", true, 0)
+	defer syncing.Close()
+	wrongChain := statusServer("test-2", false, 0)
+	defer wrongChain.Close()
+
+	cc := &ChainConfig{ChainId: "test-1"}
+	ranked := cc.probePublic([]publicEndpoint{
+		{Address: slow.URL}, {Address: syncing.URL}, {Address: wrongChain.URL}, {Address: fast.URL}, {Address: "http://127.0.0.1:1"},
+	})
+	if !reflect.DeepEqual(ranked, []string{fast.URL, slow.URL}) {
+		t.Errorf("unexpected ranking %v", ranked)
+	}
+
+	cc.public = &publicPool{ranked: ranked}
+	cc.publicFailed(fast.URL)
+	if !reflect.DeepEqual(cc.public.ranked, []string{slow.URL}) {
+		t.Errorf("failed endpoint was not removed: %v", cc.public.ranked)
+	}
+}
+
+func TestPublicCache(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "public.json")
+	saved := &publicCache{ChainId: "test-1", Updated: time.Now().UTC().Truncate(time.Second), Endpoints: []publicEndpoint{{Address: "https://rpc.example.com", Provider: "example"}}}
+	if err := savePublicCache(file, saved); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadPublicCache(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(saved, loaded) {
+		t.Errorf("cache did not round trip, got %+v", loaded)
+	}
+	if f := publicCacheFile("test/1"); filepath.Base(f) != ".tenderduty-public-test_1.json" {
+		t.Errorf("unexpected cache file %s", f)
+	}
+}
+
+func TestPublicRefreshBackoff(t *testing.T) {
+	// the chain isn't in the registry, so fetching always fails
+	p := &publicPool{endpoints: []publicEndpoint{{Address: "https://rpc.example.com"}}}
+	p.refresh("not-a-chain-1")
+	failed := p.failed
+	if failed.IsZero() {
+		t.Fatal("the failure was not recorded")
+	}
+	p.refresh("not-a-chain-1")
+	if !p.failed.Equal(failed) {
+		t.Error("the registry was fetched again before the retry interval")
+	}
+	p.failed = time.Now().Add(-publicRetryInterval)
+	p.refresh("not-a-chain-1")
+	if p.failed.Equal(failed) {
+		t.Error("the registry was not fetched again after the retry interval")
+	}
+}
@@ -4,27 +4,26 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
-	"regexp"
+	"strings"
 	"time"
 
 	dash "github.com/blockpane/tenderduty/v2/td2/dashboard"
-	rpchttp "github.com/tendermint/tendermint/rpc/client/http"
 )
 
-// newRpc sets up the rpc client used for monitoring. It will try nodes in order until a working node is found.
+// newRpc sets up the rpc client used for monitoring. It will try nodes by score until a working node is found, nodes
+// that haven't been scored yet are tried in order.
 // it will also get some initial info on the validator's status.
 func (cc *ChainConfig) newRpc() error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
 	var anyWorking bool // if healthchecks are running, we will skip to the first known good node.
 	for _, endpoint := range cc.Nodes {
-		anyWorking = anyWorking || !endpoint.down
+		anyWorking = anyWorking || endpoint.usable()
 	}
 	// grab the first working endpoint
 	tryUrl := func(u string) (msg string, down, syncing bool) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
 		_, err := url.Parse(u)
 		if err != nil {
 			msg = fmt.Sprintf("❌ could not parse url %s: (%s) %s", cc.name, u, err)
@@ -32,7 +31,7 @@ func (cc *ChainConfig) newRpc() error {
 			down = true
 			return
 		}
-		cc.client, err = rpchttp.New(u, "/websocket")
+		cc.client, err = cc.newClient(u)
 		if err != nil {
 			msg = fmt.Sprintf("❌ could not connect client for %s: (%s) %s", cc.name, u, err)
 			l(msg)
@@ -59,7 +58,18 @@ func (cc *ChainConfig) newRpc() error {
 			l(msg)
 			return
 		}
+		// a node that can't be verified isn't trusted, even if it only failed to serve the light blocks
+		if err = cc.verifyNode(u, status.SyncInfo.LatestBlockHeight, status.SyncInfo.LatestBlockHash); err != nil {
+			msg = fmt.Sprintf("🔏 could not verify %s, skipping: %v", u, err)
+			if isUnverifiable(err) {
+				msg = fmt.Sprintf("🔏 %s is serving unverifiable data, skipping: %v", u, err)
+			}
+			down = true
+			l(msg)
+			return
+		}
 		cc.noNodes = false
+		cc.setClient(false)
 		return
 	}
 	down := func(endpoint *NodeConfig, msg string) {
@@ -69,8 +79,9 @@ func (cc *ChainConfig) newRpc() error {
 		}
 		endpoint.lastMsg = msg
 	}
-	for _, endpoint := range cc.Nodes {
-		if anyWorking && endpoint.down {
+	for _, endpoint := range cc.rankedNodes() {
+		if (anyWorking && endpoint.down) || endpoint.inconsistent != "" {
+			// nodes that disagree with the majority are never used, they may be on a fork.
 			continue
 		}
 		if msg, failed, syncing := tryUrl(endpoint.Url); failed {
@@ -81,18 +92,22 @@ func (cc *ChainConfig) newRpc() error {
 		return nil
 	}
 	if cc.PublicFallback {
-		if u, ok := getRegistryUrl(cc.ChainId); ok {
-			node := guessPublicEndpoint(u)
-			l(cc.ChainId, "⛑ attemtping to use public fallback node", node)
-			if _, kk, _ := tryUrl(node); !kk {
+		endpoints := cc.publicEndpoints()
+		if len(endpoints) == 0 {
+			l("could not find a public endpoint for", cc.ChainId)
+		}
+		// the endpoints are ranked by latency, a failed endpoint is dropped so the next one is used next time.
+		for _, node := range endpoints {
+			l(cc.ChainId, "⛑ attempting to use public fallback node", node)
+			if _, failed, _ := tryUrl(node); !failed {
 				l(cc.ChainId, "⛑ connected to public endpoint", node)
 				return nil
 			}
-		} else {
-			l("could not find a public endpoint for", cc.ChainId)
+			cc.publicFailed(node)
 		}
 	}
 	cc.noNodes = true
+	cc.setClient(true)
 	alarms.clearAll(cc.name)
 	cc.lastError = "no usable RPC endpoints available for " + cc.ChainId
 	if td.EnableDash {
@@ -148,24 +163,43 @@ func (cc *ChainConfig) monitorHealth(ctx context.Context, chainName string) {
 						}
 						l("⚠️ " + node.lastMsg)
 					}
-					c, e := rpchttp.New(node.Url, "/websocket")
+					c, e := cc.newClient(node.Url)
 					if e != nil {
 						alert(e.Error())
+						return
 					}
 					cwt, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+					defer cancel()
 					status, e := c.Status(cwt)
-					cancel()
 					if e != nil {
+						node.version, node.appVersion = "", ""
 						alert("down")
 						return
 					}
 					if status.NodeInfo.Network != cc.ChainId {
+						node.version, node.appVersion = "", ""
 						alert("on the wrong network")
 						return
 					}
-					if status.SyncInfo.CatchingUp {
-						alert("not synced")
-						node.syncing = true
+					node.version = status.NodeInfo.Version
+					if node.ValidatorNode {
+						node.keyProblem = cc.checkConsensusKey(node, status)
+					}
+					// keep the last known app version if the query fails, so a flaky node doesn't cause version skew alerts
+					if info, e := c.ABCIInfo(cwt); e == nil {
+						node.appVersion = info.Response.Version
+					}
+					if failed := cc.runHealthChecks(cwt, node, c, status); len(failed) > 0 {
+						alert(strings.Join(failed, "; "))
+						node.syncing = status.SyncInfo.CatchingUp
+						return
+					}
+					if e = cc.verifyNode(node.Url, status.SyncInfo.LatestBlockHeight, status.SyncInfo.LatestBlockHash); e != nil {
+						if isUnverifiable(e) {
+							alert("serving unverifiable data: " + e.Error())
+							return
+						}
+						alert("failing light client verification: " + e.Error())
 						return
 					}
 
@@ -183,32 +217,53 @@ func (cc *ChainConfig) monitorHealth(ctx context.Context, chainName string) {
 				}(node)
 			}
 
+			// this uses the node states from the previous round of checks
+			if cc.Alerts.ConsistencyAlerts {
+				go cc.checkConsistency()
+			}
+
 			if cc.client == nil {
 				e := cc.newRpc()
 				if e != nil {
 					l("💥", cc.ChainId, e)
 				}
 			}
-			if cc.valInfo != nil {
-				cc.lastValInfo = &ValInfo{
-					Moniker:    cc.valInfo.Moniker,
-					Bonded:     cc.valInfo.Bonded,
-					Jailed:     cc.valInfo.Jailed,
-					Tombstoned: cc.valInfo.Tombstoned,
-					Missed:     cc.valInfo.Missed,
-					Window:     cc.valInfo.Window,
-					Conspub:    cc.valInfo.Conspub,
-					Valcons:    cc.valInfo.Valcons,
-				}
+			for _, v := range cc.validators() {
+				v.refreshValInfo()
 			}
-			err = cc.GetValInfo(false)
+			err = cc.checkBalances()
 			if err != nil {
-				l("❓ refreshing signing info for", cc.ValAddress, err)
+				l("❓ refreshing account balances for", cc.ChainId, err)
 			}
 		}
 	}
 }
 
+// refreshValInfo updates the validator's signing info and delegations, keeping the previous state for detecting
+// changes.
+func (cc *ChainConfig) refreshValInfo() {
+	if cc.valInfo != nil {
+		cc.lastValInfo = &ValInfo{
+			Moniker:    cc.valInfo.Moniker,
+			Bonded:     cc.valInfo.Bonded,
+			Jailed:     cc.valInfo.Jailed,
+			Tombstoned: cc.valInfo.Tombstoned,
+			Missed:     cc.valInfo.Missed,
+			Window:     cc.valInfo.Window,
+			Conspub:    cc.valInfo.Conspub,
+			Valcons:    cc.valInfo.Valcons,
+		}
+	}
+	err := cc.GetValInfo(false)
+	if err != nil {
+		l("❓ refreshing signing info for", cc.ValAddress, err)
+	}
+	err = cc.checkDelegations()
+	if err != nil {
+		l("❓ refreshing delegations for", cc.ValAddress, err)
+	}
+}
+
 func (c *Config) pingHealthcheck() {
 	if !c.Healthcheck.Enabled {
 		return
@@ -230,34 +285,3 @@ func (c *Config) pingHealthcheck() {
 		}
 	}()
 }
-
-// endpointRex matches the first a tag's hostname and port if present.
-var endpointRex = regexp.MustCompile(`//([^/:]+)(:\d+)?`)
-
-// guessPublicEndpoint attempts to deal with a shortcoming in the tendermint RPC client that doesn't allow path prefixes.
-// The cosmos.directory requires them. This is a workaround to get the actual URL for the server behind their proxy.
-// The RPC base URL will return links endpoints, and we can parse this to guess the original URL.
-func guessPublicEndpoint(u string) string {
-	resp, err := http.Get(u + "/")
-	if err != nil {
-		return u
-	}
-	b, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return u
-	}
-	_ = resp.Body.Close()
-	matches := endpointRex.FindStringSubmatch(string(b))
-	if len(matches) < 2 {
-		// didn't work
-		return u
-	}
-	proto := "https://"
-	port := ":443"
-	// will be 3 elements if there is a port no port means listening on https
-	if len(matches) == 3 && matches[2] != "" && matches[2] != ":443" {
-		proto = "http://"
-		port = matches[2]
-	}
-	return proto + matches[1] + port
-}
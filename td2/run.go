@@ -88,10 +88,24 @@ func Run(configFile, stateFile, chainConfigDirectory string, password *string) e
 	for k := range td.Chains {
 		cc := td.Chains[k]
 
+		// additional validators on a chain only need alerting, everything else is handled by the primary.
+		if cc.primary != nil {
+			go cc.watch()
+			if cc.Oracle.Enabled {
+				go cc.monitorOracle(td.ctx)
+			}
+			continue
+		}
+
 		go func(cc *ChainConfig, name string) {
 			// alert worker
 			go cc.watch()
 
+			// oracle vote monitoring:
+			if cc.Oracle.Enabled {
+				go cc.monitorOracle(td.ctx)
+			}
+
 			// node health checks:
 			go func() {
 				for {
@@ -99,6 +113,10 @@ func Run(configFile, stateFile, chainConfigDirectory string, password *string) e
 				}
 			}()
 
+			// node scoring, and switching to a better node:
+			cc.restart = make(chan struct{}, 1)
+			go cc.monitorScores(td.ctx)
+
 			// websocket subscription and occasional validator info refreshes
 			for {
 				e := cc.newRpc()
@@ -107,10 +125,13 @@ func Run(configFile, stateFile, chainConfigDirectory string, password *string) e
 					time.Sleep(5 * time.Second)
 					continue
 				}
-				e = cc.GetValInfo(true)
-				if e != nil {
-					l("🛑", cc.ChainId, e)
+				for _, v := range cc.validators() {
+					e = v.GetValInfo(true)
+					if e != nil {
+						l("🛑", v.ChainId, e)
+					}
 				}
+				cc.bootstrap()
 				cc.WsRun()
 				l(cc.ChainId, "🌀 websocket exited! Restarting monitoring")
 				time.Sleep(5 * time.Second)
@@ -144,14 +165,20 @@ func saveOnExit(stateFile string, saved chan interface{}) {
 		td.chainsMux.Lock()
 		defer td.chainsMux.Unlock()
 		blocks := make(map[string][]int)
+		historical := make(map[string][]bool)
 		// only need to save counts if the dashboard exists
 		if td.EnableDash {
 			for k, v := range td.Chains {
 				blocks[k] = v.blocksResults
+				historical[k] = v.historical
 			}
 		}
 		nodesDown := make(map[string]map[string]time.Time)
 		for k, v := range td.Chains {
+			// nodes are shared with the primary validator
+			if v.primary != nil {
+				continue
+			}
 			for _, node := range v.Nodes {
 				if node.down {
 					if nodesDown[k] == nil {
@@ -162,9 +189,10 @@ func saveOnExit(stateFile string, saved chan interface{}) {
 			}
 		}
 		b, e := json.Marshal(&savedState{
-			Alarms:    alarms,
-			Blocks:    blocks,
-			NodesDown: nodesDown,
+			Alarms:     alarms,
+			Blocks:     blocks,
+			Historical: historical,
+			NodesDown:  nodesDown,
 		})
 		if e != nil {
 			log.Println(e)
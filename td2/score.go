@@ -0,0 +1,224 @@
+package tenderduty
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	dash "github.com/blockpane/tenderduty/v2/td2/dashboard"
+)
+
+const (
+	scoreInterval = 15 * time.Second // how often every node is probed for scoring
+	scoreTimeout  = 5 * time.Second  // a probe taking longer than this counts as an error
+	scoreAlpha    = 0.3              // weight of the newest sample in the latency and error rate averages
+	scoreMargin   = 20               // how much better another node has to score before switching to it
+	scoreRounds   = 2                // consecutive rounds the active node has to be worse before switching
+)
+
+// nodeScore tracks a node's recent performance, the result is a score from 0 to 100, higher is better.
+type nodeScore struct {
+	mux       sync.RWMutex
+	latency   time.Duration // moving average of the /status response time
+	errorRate float64       // moving average of failed probes, 0 to 1
+	lag       int64         // blocks behind the highest node in the last round
+	syncing   bool
+	value     float64
+	scored    bool // false until the first probe, unscored nodes keep their configured order
+}
+
+// calculate sets the score. A node that is down or failed its last probe scores 0, otherwise points are removed for
+// being behind the other nodes (10 per block, up to 50,) catching up (50,) slow responses (1 per 50ms, up to 20,) and
+// recent errors (up to 30.)
+func (s *nodeScore) calculate(usable, failed bool) {
+	if !usable || failed {
+		s.value = 0
+		return
+	}
+	v := 100.0
+	v -= math.Min(float64(s.lag)*10, 50)
+	if s.syncing {
+		v -= 50
+	}
+	v -= math.Min(float64(s.latency.Milliseconds())/50, 20)
+	v -= s.errorRate * 30
+	s.value = math.Max(v, 0)
+}
+
+// get returns the node's score, and whether it has been probed yet.
+func (s *nodeScore) get() (float64, bool) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	return s.value, s.scored
+}
+
+// scoreSample is the result of probing a node.
+type scoreSample struct {
+	node    *NodeConfig
+	latency time.Duration
+	height  int64
+	syncing bool
+	failed  bool
+}
+
+// probeNodes gets the latency, height, and sync state of every node.
+func (cc *ChainConfig) probeNodes(ctx context.Context) []*scoreSample {
+	samples := make([]*scoreSample, len(cc.Nodes))
+	wg := sync.WaitGroup{}
+	for i, node := range cc.Nodes {
+		samples[i] = &scoreSample{node: node, failed: true}
+		wg.Add(1)
+		go func(sample *scoreSample) {
+			defer wg.Done()
+			client, err := cc.newClient(sample.node.Url)
+			if err != nil {
+				return
+			}
+			cwt, cancel := context.WithTimeout(ctx, scoreTimeout)
+			defer cancel()
+			start := time.Now()
+			status, err := client.Status(cwt)
+			if err != nil || status.NodeInfo.Network != cc.ChainId {
+				return
+			}
+			sample.latency = time.Since(start)
+			sample.height = status.SyncInfo.LatestBlockHeight
+			sample.syncing = status.SyncInfo.CatchingUp
+			sample.failed = false
+		}(samples[i])
+	}
+	wg.Wait()
+	return samples
+}
+
+// updateScores probes the nodes and updates their scores, the lag is measured against the highest node.
+func (cc *ChainConfig) updateScores(ctx context.Context) {
+	samples := cc.probeNodes(ctx)
+	var best int64
+	for _, sample := range samples {
+		if !sample.failed && sample.height > best {
+			best = sample.height
+		}
+	}
+	for _, sample := range samples {
+		s := &sample.node.score
+		s.mux.Lock()
+		failed := 0.0
+		if sample.failed {
+			failed = 1
+		} else {
+			if s.scored {
+				s.latency = time.Duration(scoreAlpha*float64(sample.latency) + (1-scoreAlpha)*float64(s.latency))
+			} else {
+				s.latency = sample.latency
+			}
+			s.lag = best - sample.height
+			s.syncing = sample.syncing
+		}
+		s.errorRate = scoreAlpha*failed + (1-scoreAlpha)*s.errorRate
+		s.calculate(sample.node.usable(), sample.failed)
+		s.scored = true
+		s.mux.Unlock()
+		if td.Prom {
+			td.statsChan <- cc.mkUpdate(metricNodeScore, s.value, sample.node.Url)
+		}
+	}
+}
+
+// rankedNodes returns the chain's nodes, highest score first. Nodes with the same score keep their configured order.
+func (cc *ChainConfig) rankedNodes() []*NodeConfig {
+	nodes := append([]*NodeConfig{}, cc.Nodes...)
+	sort.SliceStable(nodes, func(i, j int) bool {
+		a, _ := nodes[i].score.get()
+		b, _ := nodes[j].score.get()
+		return a > b
+	})
+	return nodes
+}
+
+// activeUrl is the node used by the rpc client and the first websocket, empty when monitoring isn't running.
+func (cc *ChainConfig) activeUrl() string {
+	if cc.primary != nil {
+		return cc.primary.activeUrl()
+	}
+	u, _ := cc.activeNode.Load().(string)
+	return u
+}
+
+// shouldSwitch decides if monitoring should switch from the active node to the best one. worse is the number of
+// consecutive rounds the active node has scored at least scoreMargin below the best node, the updated count is
+// returned. A node scoring 0 is switched from immediately.
+func shouldSwitch(activeScore, bestScore float64, worse int) (bool, int) {
+	if bestScore == 0 || bestScore-activeScore < scoreMargin {
+		return false, 0
+	}
+	worse += 1
+	if activeScore > 0 && worse < scoreRounds {
+		return false, worse
+	}
+	return true, 0
+}
+
+// monitorScores scores the nodes until the context is cancelled, switching to a better node when the active one
+// degrades.
+func (cc *ChainConfig) monitorScores(ctx context.Context) {
+	// with only public endpoints there is nothing to score or switch to
+	if len(cc.Nodes) == 0 {
+		return
+	}
+	tick := time.NewTicker(scoreInterval)
+	defer tick.Stop()
+	var worse int // consecutive rounds the active node scored worse than the best by at least scoreMargin
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+			cc.updateScores(ctx)
+			active := cc.activeUrl()
+			if active == "" {
+				worse = 0
+				continue
+			}
+			best := cc.rankedNodes()[0]
+			if best.Url == active {
+				worse = 0
+				continue
+			}
+			bestScore, _ := best.score.get()
+			// a public endpoint isn't scored, any usable configured node is better.
+			activeScore := 0.0
+			if node := cc.node(active); node != nil {
+				activeScore, _ = node.score.get()
+			}
+			var switching bool
+			if switching, worse = shouldSwitch(activeScore, bestScore, worse); switching {
+				cc.failover(active, activeScore, best, bestScore)
+			}
+		}
+	}
+}
+
+// failover restarts monitoring, which reconnects using the best node. Blocks that are missed while reconnecting are
+// backfilled.
+func (cc *ChainConfig) failover(active string, activeScore float64, best *NodeConfig, bestScore float64) {
+	l(fmt.Sprintf("🔀 %-12s switching from %s (score %.0f) to %s (score %.0f)", cc.ChainId, active, activeScore, best.Url, bestScore))
+	cc.requestRestart()
+}
+
+// nodeScores lists the score of every node for the dashboard.
+func (cc *ChainConfig) nodeScores() []dash.NodeScore {
+	active := cc.activeUrl()
+	scores := make([]dash.NodeScore, 0, len(cc.Nodes))
+	for _, node := range cc.rankedNodes() {
+		score, scored := node.score.get()
+		if !scored {
+			continue
+		}
+		scores = append(scores, dash.NodeScore{Url: node.Url, Score: math.Round(score), Active: node.Url == active})
+	}
+	return scores
+}
@@ -0,0 +1,92 @@
+package tenderduty
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNodeScore(t *testing.T) {
+	for name, tc := range map[string]struct {
+		score   *nodeScore
+		usable  bool
+		failed  bool
+		expects float64
+	}{
+		"healthy":  {&nodeScore{latency: 50 * time.Millisecond}, true, false, 99},
+		"down":     {&nodeScore{}, false, false, 0},
+		"failed":   {&nodeScore{}, true, true, 0},
+		"lagging":  {&nodeScore{lag: 2}, true, false, 80},
+		"far back": {&nodeScore{lag: 100}, true, false, 50},
+		"syncing":  {&nodeScore{lag: 100, syncing: true}, true, false, 0},
+		"slow":     {&nodeScore{latency: 5 * time.Second}, true, false, 80},
+		"errors":   {&nodeScore{errorRate: 0.5}, true, false, 85},
+	} {
+		tc.score.calculate(tc.usable, tc.failed)
+		if tc.score.value != tc.expects {
+			t.Errorf("%s: expected %.0f, got %.2f", name, tc.expects, tc.score.value)
+		}
+	}
+}
+
+func TestRankedNodes(t *testing.T) {
+	a, b, c := &NodeConfig{Url: "a"}, &NodeConfig{Url: "b"}, &NodeConfig{Url: "c"}
+	cc := &ChainConfig{Nodes: []*NodeConfig{a, b, c}}
+	if ranked := cc.rankedNodes(); ranked[0] != a || ranked[1] != b || ranked[2] != c {
+		t.Error("unscored nodes should keep their configured order")
+	}
+	b.score.value, c.score.value = 90, 90
+	if ranked := cc.rankedNodes(); ranked[0] != b || ranked[1] != c || ranked[2] != a {
+		t.Errorf("unexpected order %s %s %s", ranked[0].Url, ranked[1].Url, ranked[2].Url)
+	}
+}
+
+func TestShouldSwitch(t *testing.T) {
+	for name, tc := range map[string]struct {
+		active, best float64
+		worse        int
+		switching    bool
+		expectWorse  int
+	}{
+		"within margin":      {active: 81, best: 100, worse: 1, switching: false, expectWorse: 0},
+		"at margin":          {active: 80, best: 100, worse: 0, switching: false, expectWorse: 1},
+		"second round":       {active: 80, best: 100, worse: 1, switching: true, expectWorse: 0},
+		"recovered":          {active: 95, best: 100, worse: 1, switching: false, expectWorse: 0},
+		"active down":        {active: 0, best: 60, worse: 0, switching: true, expectWorse: 0},
+		"no usable node":     {active: 0, best: 0, worse: 3, switching: false, expectWorse: 0},
+		"best barely better": {active: 0, best: 19, worse: 0, switching: false, expectWorse: 0},
+	} {
+		switching, worse := shouldSwitch(tc.active, tc.best, tc.worse)
+		if switching != tc.switching || worse != tc.expectWorse {
+			t.Errorf("%s: expected %v and %d rounds, got %v and %d", name, tc.switching, tc.expectWorse, switching, worse)
+		}
+	}
+}
+
+func TestFailover(t *testing.T) {
+	cc := &ChainConfig{ChainId: "test-1", restart: make(chan struct{}, 1)}
+	best := &NodeConfig{Url: "b"}
+	cc.failover("a", 10, best, 90)
+	// a second switch before monitoring restarted shouldn't block
+	cc.failover("a", 10, best, 90)
+	select {
+	case <-cc.restart:
+	default:
+		t.Error("failover did not signal a restart")
+	}
+}
+
+func TestMonitorScoresWithoutNodes(t *testing.T) {
+	cc := &ChainConfig{ChainId: "test-1", PublicFallback: true}
+	cc.activeNode.Store("https://public.example.com:443")
+	done := make(chan struct{})
+	go func() {
+		cc.monitorScores(context.Background())
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("monitorScores kept running without any nodes")
+	}
+}
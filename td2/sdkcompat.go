@@ -0,0 +1,191 @@
+package tenderduty
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	slashing "github.com/cosmos/cosmos-sdk/x/slashing/types"
+	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// The staking and slashing query responses are decoded using the cosmos-sdk v0.45 types first. Newer releases add
+// fields, and some chains change existing ones, which can break the generated decoders. If that happens the fields
+// needed are read directly from the wire format, their field numbers have not changed from v0.45 through v0.50.
+
+// stakingValidator holds the fields used from a staking Validator.
+type stakingValidator struct {
+	pubkeyType string // type URL of the consensus pubkey's Any
+	pubkey     []byte
+	moniker    string
+	jailed     bool
+	bonded     bool
+}
+
+// decodeValidator decodes a QueryValidatorResponse.
+func decodeValidator(b []byte) (*stakingValidator, error) {
+	val := &staking.QueryValidatorResponse{}
+	if err := val.Unmarshal(b); err == nil {
+		if val.Validator.ConsensusPubkey == nil {
+			return nil, errors.New("got invalid consensus pubkey")
+		}
+		return &stakingValidator{
+			pubkeyType: val.Validator.ConsensusPubkey.TypeUrl,
+			pubkey:     val.Validator.ConsensusPubkey.Value,
+			moniker:    val.Validator.GetMoniker(),
+			jailed:     val.Validator.Jailed,
+			bonded:     val.Validator.Status == staking.Bonded,
+		}, nil
+	}
+	return decodeValidatorWire(b)
+}
+
+// decodeValidatorWire reads the fields used from a QueryValidatorResponse's wire format.
+func decodeValidatorWire(b []byte) (*stakingValidator, error) {
+	// QueryValidatorResponse.validator = 1
+	// Validator: consensus_pubkey = 2, jailed = 3, status = 4, description = 7
+	// Any: type_url = 1, value = 2
+	// Description: moniker = 1
+	v := &stakingValidator{}
+	typeUrl, err := findBytes(b, 1, 1, 2)
+	if err != nil {
+		return nil, err
+	}
+	v.pubkeyType = string(typeUrl)
+	if v.pubkey, err = findBytes(b, 2, 1, 2); err != nil {
+		return nil, err
+	}
+	if v.pubkeyType == "" || len(v.pubkey) == 0 {
+		return nil, errors.New("got invalid consensus pubkey")
+	}
+	moniker, err := findBytes(b, 1, 1, 7)
+	if err != nil {
+		return nil, err
+	}
+	v.moniker = string(moniker)
+	jailed, err := findVarint(b, 3, 1)
+	if err != nil {
+		return nil, err
+	}
+	v.jailed = jailed == 1
+	status, err := findVarint(b, 4, 1)
+	if err != nil {
+		return nil, err
+	}
+	v.bonded = status == uint64(staking.Bonded)
+	return v, nil
+}
+
+// decodeSigningInfo decodes a QuerySigningInfoResponse, returning the missed blocks counter and if tombstoned.
+func decodeSigningInfo(b []byte) (missed int64, tombstoned bool, err error) {
+	slash := &slashing.QuerySigningInfoResponse{}
+	if err = slash.Unmarshal(b); err == nil {
+		return slash.ValSigningInfo.MissedBlocksCounter, slash.ValSigningInfo.Tombstoned, nil
+	}
+	return decodeSigningInfoWire(b)
+}
+
+// decodeSigningInfoWire reads the fields used from a QuerySigningInfoResponse's wire format.
+func decodeSigningInfoWire(b []byte) (missed int64, tombstoned bool, err error) {
+	// QuerySigningInfoResponse.val_signing_info = 1
+	// ValidatorSigningInfo: tombstoned = 5, missed_blocks_counter = 6
+	m, err := findVarint(b, 6, 1)
+	if err != nil {
+		return
+	}
+	t, err := findVarint(b, 5, 1)
+	if err != nil {
+		return
+	}
+	return int64(m), t == 1, nil
+}
+
+// decodeSlashingParams decodes a slashing QueryParamsResponse, returning the signed blocks window.
+func decodeSlashingParams(b []byte) (int64, error) {
+	params := &slashing.QueryParamsResponse{}
+	if err := params.Unmarshal(b); err == nil {
+		return params.Params.SignedBlocksWindow, nil
+	}
+	return decodeSlashingParamsWire(b)
+}
+
+// decodeSlashingParamsWire reads the signed blocks window from a slashing QueryParamsResponse's wire format.
+func decodeSlashingParamsWire(b []byte) (int64, error) {
+	// QueryParamsResponse.params = 1
+	// Params: signed_blocks_window = 1
+	window, err := findVarint(b, 1, 1)
+	if err != nil {
+		return 0, err
+	}
+	if window == 0 {
+		return 0, errors.New("slashing params did not include the signed blocks window")
+	}
+	return int64(window), nil
+}
+
+// findBytes returns the value of a length-delimited field (bytes, string, or message) from a serialized protobuf
+// message, descending into the message fields listed in path first. Missing fields return nil.
+func findBytes(b []byte, field protowire.Number, path ...protowire.Number) ([]byte, error) {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		if typ == protowire.BytesType && ((len(path) > 0 && num == path[0]) || (len(path) == 0 && num == field)) {
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			if len(path) == 0 {
+				return v, nil
+			}
+			return findBytes(v, field, path[1:]...)
+		}
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+	}
+	return nil, nil
+}
+
+// getSdkVersion asks a node for the cosmos-sdk version the application was built with, using
+// /cosmos.base.tendermint.v1beta1.Service/GetNodeInfo. GetNodeInfoResponse.application_version is field 2, and
+// VersionInfo.cosmos_sdk_version is field 8.
+func (cc *ChainConfig) getSdkVersion(ctx context.Context) (string, error) {
+	resp, err := cc.query(ctx, "/cosmos.base.tendermint.v1beta1.Service/GetNodeInfo", nil)
+	if err != nil {
+		return "", err
+	}
+	v, err := findBytes(resp, 8, 2)
+	if err != nil {
+		return "", err
+	}
+	if len(v) == 0 {
+		return "", errors.New("node info did not include the cosmos-sdk version")
+	}
+	return strings.TrimSpace(string(v)), nil
+}
+
+// detectSdkVersion looks up the cosmos-sdk version, it is only used for reporting. Decoding does not depend on it
+// since forks often report a version that doesn't match their types. If the lookup fails the version is left empty so
+// it is tried again on the next refresh, the failure is only logged once.
+func (cc *ChainConfig) detectSdkVersion(ctx context.Context) {
+	if cc.sdkVersion != "" || cc.Backend == backendConsensus {
+		return
+	}
+	v, err := cc.getSdkVersion(ctx)
+	if err != nil {
+		if !cc.sdkUnknown {
+			l(fmt.Sprintf("⚠️ %-12s could not determine the cosmos-sdk version, will retry: %v", cc.ChainId, err))
+			cc.sdkUnknown = true
+		}
+		return
+	}
+	cc.sdkVersion, cc.sdkUnknown = v, false
+	l(fmt.Sprintf("⚙️ %-12s is running cosmos-sdk %s", cc.ChainId, v))
+}
@@ -0,0 +1,198 @@
+package tenderduty
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	dash "github.com/blockpane/tenderduty/v2/td2/dashboard"
+	cstypes "github.com/tendermint/tendermint/consensus/types"
+)
+
+// consensusSummary is a node's view of the consensus round in progress, used to explain why a chain is stalled.
+type consensusSummary struct {
+	height       int64
+	round        int32
+	step         string
+	prevotes     float64 // fraction of voting power that has prevoted in the current round
+	precommits   float64 // fraction of voting power that has precommitted in the current round
+	prevoted     bool
+	precommitted bool
+}
+
+func (cs *consensusSummary) String() string {
+	var voted string
+	switch {
+	case cs.prevoted && cs.precommitted:
+		voted = "our validator prevoted and precommitted"
+	case cs.prevoted:
+		voted = "our validator prevoted but has not precommitted"
+	case cs.precommitted:
+		voted = "our validator precommitted but its prevote was not seen"
+	default:
+		voted = "our validator has not voted"
+	}
+	return fmt.Sprintf("consensus is at height %d round %d (%s), %.0f%% prevotes, %.0f%% precommits seen, %s",
+		cs.height, cs.round, cs.step, 100*cs.prevotes, 100*cs.precommits, voted)
+}
+
+// rawRoundVotes is a round from the height vote set, votes are in the same order as the validator set.
+type rawRoundVotes struct {
+	Round              int32    `json:"round"`
+	Prevotes           []string `json:"prevotes"`
+	PrevotesBitArray   string   `json:"prevotes_bit_array"`
+	Precommits         []string `json:"precommits"`
+	PrecommitsBitArray string   `json:"precommits_bit_array"`
+}
+
+// rawRoundState holds the fields used from either /consensus_state or /dump_consensus_state, the simple state has
+// the height, round, and step in a single string, and names the vote set differently.
+type rawRoundState struct {
+	HeightRoundStep string          `json:"height/round/step"`
+	HeightVoteSet   []rawRoundVotes `json:"height_vote_set"`
+
+	Height string          `json:"height"`
+	Round  int32           `json:"round"`
+	Step   uint8           `json:"step"`
+	Votes  []rawRoundVotes `json:"votes"`
+}
+
+// votedFraction extracts the fraction of voting power from a vote bit array string, which looks like
+// "BA{100:xx_x...} 6703/9999 = 0.67"
+func votedFraction(bitArray string) float64 {
+	i := strings.LastIndex(bitArray, " = ")
+	if i < 0 {
+		return 0
+	}
+	f, _ := strconv.ParseFloat(strings.TrimSpace(bitArray[i+3:]), 64)
+	return f
+}
+
+// hasVoted checks if any of the votes are from the validator. A vote string only includes a fingerprint of the
+// validator's address (the first six bytes,) ie "Vote{12:ABCDEF012345 100/00/SIGNED_MSG_TYPE_PREVOTE(Prevote) ...}"
+func hasVoted(votes []string, address []byte) bool {
+	if len(address) < 6 {
+		return false
+	}
+	fingerprint := fmt.Sprintf(":%X ", address[:6])
+	for _, vote := range votes {
+		if strings.Contains(vote, fingerprint) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRoundState summarizes the round state returned by /consensus_state or /dump_consensus_state.
+func parseRoundState(b []byte, address []byte) (*consensusSummary, error) {
+	rs := &rawRoundState{}
+	if err := json.Unmarshal(b, rs); err != nil {
+		return nil, err
+	}
+	cs := &consensusSummary{}
+	votes := rs.Votes
+	if rs.HeightRoundStep != "" {
+		hrs := strings.Split(rs.HeightRoundStep, "/")
+		if len(hrs) != 3 {
+			return nil, errors.New("invalid height/round/step: " + rs.HeightRoundStep)
+		}
+		cs.height, _ = strconv.ParseInt(hrs[0], 10, 64)
+		round, _ := strconv.ParseInt(hrs[1], 10, 32)
+		cs.round = int32(round)
+		step, _ := strconv.ParseUint(hrs[2], 10, 8)
+		rs.Step = uint8(step)
+		votes = rs.HeightVoteSet
+	} else {
+		cs.height, _ = strconv.ParseInt(rs.Height, 10, 64)
+		cs.round = rs.Round
+	}
+	if cs.height == 0 {
+		return nil, errors.New("round state did not include a height")
+	}
+	cs.step = strings.TrimPrefix(cstypes.RoundStepType(rs.Step).String(), "RoundStep")
+	for _, rv := range votes {
+		if rv.Round != cs.round {
+			continue
+		}
+		cs.prevotes = votedFraction(rv.PrevotesBitArray)
+		cs.precommits = votedFraction(rv.PrecommitsBitArray)
+		cs.prevoted = hasVoted(rv.Prevotes, address)
+		cs.precommitted = hasVoted(rv.Precommits, address)
+	}
+	return cs, nil
+}
+
+// stallDiagnostics asks the healthy nodes for their view of consensus, returning a summary from the first one that
+// responds. /consensus_state is tried first since /dump_consensus_state also
+// includes every peer's state and is much larger.
+func (cc *ChainConfig) stallDiagnostics(ctx context.Context) (*consensusSummary, error) {
+	if cc.valInfo == nil {
+		return nil, errors.New("validator info is not available")
+	}
+	for _, node := range cc.Nodes {
+		if node.down {
+			continue
+		}
+		client, err := cc.newClient(node.Url)
+		if err != nil {
+			continue
+		}
+		cwt, cancel := context.WithTimeout(ctx, 10*time.Second)
+		var raw json.RawMessage
+		if state, err := client.ConsensusState(cwt); err == nil {
+			raw = state.RoundState
+		} else if dump, err := client.DumpConsensusState(cwt); err == nil {
+			raw = dump.RoundState
+		}
+		cancel()
+		if raw == nil {
+			continue
+		}
+		summary, err := parseRoundState(raw, cc.valInfo.Conspub)
+		if err != nil {
+			l("❓ could not parse consensus state from", node.Url, err)
+			continue
+		}
+		return summary, nil
+	}
+	return nil, errors.New("no healthy nodes returned the consensus state for " + cc.ChainId)
+}
+
+// updateStalled shows the stall diagnostics on the dashboard, there are no new blocks to trigger an update.
+func (cc *ChainConfig) updateStalled(info string) {
+	cc.lastError = time.Now().UTC().String() + " " + info
+	if !td.EnableDash {
+		return
+	}
+	healthyNodes := 0
+	for i := range cc.Nodes {
+		if cc.Nodes[i].usable() {
+			healthyNodes += 1
+		}
+	}
+	td.updateChan <- &dash.ChainStatus{
+		MsgType:      "status",
+		Name:         cc.name,
+		ChainId:      cc.ChainId,
+		Moniker:      cc.valInfo.Moniker,
+		Bonded:       cc.valInfo.Bonded,
+		Jailed:       cc.valInfo.Jailed,
+		Tombstoned:   cc.valInfo.Tombstoned,
+		Missed:       cc.valInfo.Missed,
+		Window:       cc.valInfo.Window,
+		Nodes:        len(cc.Nodes),
+		HealthyNodes: healthyNodes,
+		ActiveAlerts: alarms.getCount(cc.name),
+		Height:       cc.lastBlockNum,
+		LastError:    cc.lastError,
+		Versions:     cc.nodeVersions(),
+		Scores:       cc.nodeScores(),
+		SdkVersion:   cc.sdkVersion,
+		Blocks:       cc.blocksResults,
+		Historical:   cc.historical,
+	}
+}
@@ -0,0 +1,71 @@
+package tenderduty
+
+import (
+	"testing"
+)
+
+func TestParseRoundState(t *testing.T) {
+	address := ToBytes("9C17C94F7313BB4D6E064287BEEDE5D3888E8855")
+
+	// trimmed /consensus_state response
+	simple := []byte(`{
+		"height/round/step": "1234/1/6",
+		"start_time": "2022-11-08T21:41:43.180386535Z",
+		"height_vote_set": [
+			{
+				"round": 0,
+				"prevotes": ["nil-Vote", "nil-Vote"],
+				"prevotes_bit_array": "BA{2:__} 0/100 = 0.00",
+				"precommits": ["nil-Vote", "nil-Vote"],
+				"precommits_bit_array": "BA{2:__} 0/100 = 0.00"
+			},
+			{
+				"round": 1,
+				"prevotes": ["Vote{0:9C17C94F7313 1234/01/SIGNED_MSG_TYPE_PREVOTE(Prevote) 8E9A0D7FA4CF 1B9C2CBB4E2A @ 2022-11-08T21:41:44.196Z}", "nil-Vote"],
+				"prevotes_bit_array": "BA{2:x_} 60/100 = 0.60",
+				"precommits": ["nil-Vote", "nil-Vote"],
+				"precommits_bit_array": "BA{2:__} 0/100 = 0.00"
+			}
+		]
+	}`)
+	cs, err := parseRoundState(simple, address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cs.height != 1234 || cs.round != 1 || cs.step != "Precommit" {
+		t.Errorf("expected 1234/1/Precommit, got %d/%d/%s", cs.height, cs.round, cs.step)
+	}
+	if cs.prevotes != 0.6 || cs.precommits != 0 || !cs.prevoted || cs.precommitted {
+		t.Errorf("unexpected votes: %+v", cs)
+	}
+
+	// trimmed /dump_consensus_state response
+	dump := []byte(`{
+		"height": "1234",
+		"round": 0,
+		"step": 4,
+		"votes": [
+			{
+				"round": 0,
+				"prevotes": ["nil-Vote", "Vote{1:0A2B3C4D5E6F 1234/00/SIGNED_MSG_TYPE_PREVOTE(Prevote) 8E9A0D7FA4CF 1B9C2CBB4E2A @ 2022-11-08T21:41:44.196Z}"],
+				"prevotes_bit_array": "BA{2:_x} 40/100 = 0.40",
+				"precommits": ["nil-Vote", "nil-Vote"],
+				"precommits_bit_array": "BA{2:__} 0/100 = 0.00"
+			}
+		]
+	}`)
+	cs, err = parseRoundState(dump, address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cs.height != 1234 || cs.round != 0 || cs.step != "Prevote" {
+		t.Errorf("expected 1234/0/Prevote, got %d/%d/%s", cs.height, cs.round, cs.step)
+	}
+	if cs.prevotes != 0.4 || cs.prevoted {
+		t.Errorf("unexpected votes: %+v", cs)
+	}
+
+	if _, err = parseRoundState([]byte(`{}`), address); err == nil {
+		t.Error("expected an error for an empty round state")
+	}
+}
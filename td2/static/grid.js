@@ -126,6 +126,19 @@ function legend() {
     offset += gridW + gridW/2
     ctx.fillStyle = 'grey'
     ctx.fillText("no data", offset, gridH/1.2)
+
+    offset += 62 * scale
+    grad = ctx.createLinearGradient(offset, 0, offset+gridW, gridH)
+    grad.addColorStop(0, 'rgb(123,255,66)');
+    grad.addColorStop(0.3, 'rgb(240,255,128)');
+    grad.addColorStop(0.8, 'rgb(169,250,149)');
+    ctx.fillStyle = grad
+    ctx.globalAlpha = 0.45
+    ctx.fillRect(offset, 0, gridW, gridH)
+    ctx.globalAlpha = 1
+    offset += gridW + gridW/2
+    ctx.fillStyle = 'grey'
+    ctx.fillText("historical", offset, gridH/1.2)
 }
 
 function drawSeries(multiStates) {
@@ -144,6 +157,9 @@ function drawSeries(multiStates) {
             ctx.fillStyle = textColor
             ctx.fillText(multiStates.Status[j].name, 5, (j*gridH)+(gridH*2)-6, gridTextMax)
 
+            // blocks loaded at startup are drawn faded
+            const historical = multiStates.Status[j].historical || []
+
             for (let i = 0; i < multiStates.Status[j].blocks.length; i++) {
                 crossThrough = false
                 const grad = ctx.createLinearGradient((i*gridW)+gridTextW, (gridH*j), (i * gridW) + gridW +gridTextW, (gridH*j))
@@ -182,7 +198,9 @@ function drawSeries(multiStates) {
                 }
                 ctx.clearRect((i*gridW)+gridTextW, gridH+(gridH*j), gridW, gridH)
                 ctx.fillStyle = grad
+                ctx.globalAlpha = historical[i] ? 0.45 : 1
                 ctx.fillRect((i*gridW)+gridTextW, gridH+(gridH*j), gridW, gridH)
+                ctx.globalAlpha = 1
 
                 // line between rows
                 if (i > 0) {
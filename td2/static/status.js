@@ -112,6 +112,22 @@ function updateTable(status) {
         if (status.Status[i].healthy_nodes < status.Status[i].nodes) {
             nodes = "<strong><span uk-icon='arrow-down' style='color: darkorange'></span>" + nodes + "</strong>"
         }
+        if (status.Status[i].scores && status.Status[i].scores.length > 0) {
+            const scores = status.Status[i].scores.map(function (ns) { return `${ns.url}: ${ns.score}${ns.active ? " (active)" : ""}` }).join(", ")
+            nodes = `<span uk-tooltip="${_.escape(scores)}">${nodes}</span>`
+        }
+
+        let version = "&nbsp;"
+        if (status.Status[i].versions && status.Status[i].versions.length > 0) {
+            const v = status.Status[i].versions
+            version = _.escape(v[0].app)
+            if (v.length > 1) {
+                const all = v.map(function (nv) { return `${nv.app} / tm ${nv.tendermint} (${nv.nodes})` }).join(", ")
+                version = `<strong><span uk-icon='warning' uk-tooltip="${_.escape(all)}" style='color: darkorange'></span>${version}</strong>`
+            } else if (status.Status[i].sdk_version) {
+                version = `<span uk-tooltip="cosmos-sdk ${_.escape(status.Status[i].sdk_version)} / tm ${_.escape(v[0].tendermint)}">${version}</span>`
+            }
+        }
 
         let heightClass = ""
         if (blocks.get(status.Status[i].chain_id) !== status.Status[i].height){
@@ -131,7 +147,8 @@ function updateTable(status) {
         }
         r.insertCell(4).innerHTML = `<div style="text-align: center">${bonded}</div>`
         r.insertCell(5).innerHTML = `<div uk-grid>${window}</div>`
-        r.insertCell(6).innerHTML = `<div class="uk-text-center">${nodes}</div>`
+        r.insertCell(6).innerHTML = `<div class="uk-text-center uk-text-truncate">${version}</div>`
+        r.insertCell(7).innerHTML = `<div class="uk-text-center">${nodes}</div>`
     }
 }
 
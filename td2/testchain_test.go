@@ -0,0 +1,93 @@
+package tenderduty
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/crypto/tmhash"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	tmversion "github.com/tendermint/tendermint/proto/tendermint/version"
+	"github.com/tendermint/tendermint/types"
+	"github.com/tendermint/tendermint/version"
+)
+
+// testChain is a chain of signed headers for light client tests. Each height is signed by every validator in its set.
+type testChain struct {
+	chainId string
+	headers map[int64]*types.SignedHeader
+	vals    map[int64]*types.ValidatorSet
+}
+
+// newValSet returns a validator set with n equally weighted validators, and their keys by address. Keys are derived
+// from the prefix, so the same prefix always gives the same set.
+func newValSet(t *testing.T, prefix string, n int) (*types.ValidatorSet, map[string]types.PrivValidator) {
+	t.Helper()
+	vals := make([]*types.Validator, 0, n)
+	keys := make(map[string]types.PrivValidator)
+	for i := 0; i < n; i++ {
+		priv := ed25519.GenPrivKeyFromSecret([]byte(fmt.Sprintf("%s-%d", prefix, i)))
+		keys[string(priv.PubKey().Address())] = types.NewMockPVWithParams(priv, false, false)
+		vals = append(vals, types.NewValidator(priv.PubKey(), 10))
+	}
+	return types.NewValidatorSet(vals), keys
+}
+
+// newTestChain builds the headers from height 1 to the number of sets given, sets[h-1] signs height h. The last
+// header's time is a minute ago.
+func newTestChain(t *testing.T, chainId string, sets []*types.ValidatorSet, keys map[string]types.PrivValidator) *testChain {
+	t.Helper()
+	tc := &testChain{chainId: chainId, headers: make(map[int64]*types.SignedHeader), vals: make(map[int64]*types.ValidatorSet)}
+	start := time.Now().Add(-time.Minute - time.Duration(len(sets))*6*time.Second).UTC()
+	lastId := types.BlockID{}
+	for i, vals := range sets {
+		height := int64(i + 1)
+		next := vals
+		if i+1 < len(sets) {
+			next = sets[i+1]
+		}
+		header := &types.Header{
+			Version:            tmversion.Consensus{Block: version.BlockProtocol},
+			ChainID:            chainId,
+			Height:             height,
+			Time:               start.Add(time.Duration(height) * 6 * time.Second),
+			LastBlockID:        lastId,
+			ValidatorsHash:     vals.Hash(),
+			NextValidatorsHash: next.Hash(),
+			ConsensusHash:      types.HashConsensusParams(*types.DefaultConsensusParams()),
+			AppHash:            tmhash.Sum([]byte(fmt.Sprintf("app-%d", height))),
+			ProposerAddress:    vals.GetProposer().Address,
+		}
+		id := types.BlockID{Hash: header.Hash(), PartSetHeader: types.PartSetHeader{Total: 1, Hash: tmhash.Sum(header.Hash())}}
+		tc.headers[height] = &types.SignedHeader{Header: header, Commit: signCommit(t, chainId, header, id, vals, keys)}
+		tc.vals[height] = vals
+		lastId = id
+	}
+	return tc
+}
+
+// signCommit has every validator in the set sign the block.
+func signCommit(t *testing.T, chainId string, header *types.Header, id types.BlockID, vals *types.ValidatorSet, keys map[string]types.PrivValidator) *types.Commit {
+	t.Helper()
+	voteSet := types.NewVoteSet(chainId, header.Height, 0, tmproto.PrecommitType, vals)
+	for i, val := range vals.Validators {
+		vote := &types.Vote{
+			Type:             tmproto.PrecommitType,
+			Height:           header.Height,
+			BlockID:          id,
+			Timestamp:        header.Time.Add(time.Second),
+			ValidatorAddress: val.Address,
+			ValidatorIndex:   int32(i),
+		}
+		p := vote.ToProto()
+		if err := keys[string(val.Address)].SignVote(chainId, p); err != nil {
+			t.Fatal(err)
+		}
+		vote.Signature = p.Signature
+		if _, err := voteSet.AddVote(vote); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return voteSet.MakeCommit()
+}
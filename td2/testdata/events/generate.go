@@ -0,0 +1,336 @@
+//go:build ignore
+
+// generate writes the websocket event fixtures for each consensus version. The blocks, commits and votes come from a
+// four validator test chain signed with tendermint's own types, so hashes and signatures are real and the blocks pass
+// ValidateBasic. The v0.37 and v0.38 frames use the same blocks with the event encodings those versions changed.
+//
+// Run from the repository root with: go run ./td2/testdata/events/generate.go
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/types/bech32"
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	tmjson "github.com/tendermint/tendermint/libs/json"
+	"github.com/tendermint/tendermint/p2p"
+	tmproto "github.com/tendermint/tendermint/proto/tendermint/types"
+	tmversion "github.com/tendermint/tendermint/proto/tendermint/version"
+	coretypes "github.com/tendermint/tendermint/rpc/core/types"
+	"github.com/tendermint/tendermint/types"
+	"github.com/tendermint/tendermint/version"
+)
+
+const (
+	chainId = "testnet-1"
+	dir     = "td2/testdata/events"
+)
+
+var (
+	genesis = time.Date(2023, 6, 1, 12, 0, 0, 123456789, time.UTC)
+	pvs     = make(map[string]types.PrivValidator)
+	valSet  *types.ValidatorSet
+)
+
+func main() {
+	vals := make([]*types.Validator, 0)
+	for i := 0; i < 4; i++ {
+		priv := ed25519.GenPrivKeyFromSecret([]byte(fmt.Sprintf("validator-%d", i)))
+		pvs[string(priv.PubKey().Address())] = types.NewMockPVWithParams(priv, false, false)
+		vals = append(vals, types.NewValidator(priv.PubKey(), 10))
+	}
+	valSet = types.NewValidatorSet(vals)
+	// the watched validator signs and proposes, the absent one is missing from every commit and slashed at 1234
+	watched, absent := valSet.Validators[0], valSet.Validators[3]
+	fmt.Printf("watched %s %s\nabsent  %s %s\n", watched.Address, valcons(watched.Address), absent.Address, valcons(absent.Address))
+
+	b1232 := makeBlock(1232, types.BlockID{}, &types.Commit{}, valSet.Validators[1].Address)
+	b1233 := makeBlock(1233, blockId(b1232), commit(b1232, absent.Address), valSet.Validators[2].Address)
+	b1234 := makeBlock(1234, blockId(b1233), commit(b1233, absent.Address), watched.Address)
+	b1235 := makeBlock(1235, blockId(b1234), commit(b1234, absent.Address), watched.Address)
+
+	// SDK v0.45 downtime slashing, emitted in BeginBlock. The validator update removing it is in EndBlock.
+	slashed := valcons(absent.Address)
+	begin := []abci.Event{
+		{Type: "liveness", Attributes: []abci.EventAttribute{
+			{Key: []byte("address"), Value: []byte(slashed), Index: true},
+			{Key: []byte("missed_blocks"), Value: []byte("501"), Index: true},
+			{Key: []byte("height"), Value: []byte("1234"), Index: true},
+		}},
+		{Type: "slash", Attributes: []abci.EventAttribute{
+			{Key: []byte("address"), Value: []byte(slashed), Index: true},
+			{Key: []byte("power"), Value: []byte("10"), Index: true},
+			{Key: []byte("reason"), Value: []byte("missing_signature"), Index: true},
+			{Key: []byte("jailed"), Value: []byte(slashed), Index: true},
+		}},
+	}
+	end := abci.ResponseEndBlock{ValidatorUpdates: []abci.ValidatorUpdate{abci.Ed25519ValidatorUpdate(absent.PubKey.Bytes(), 0)}}
+	blockEvents := map[string][]string{
+		"tm.event":               {"NewBlock"},
+		"liveness.address":       {slashed},
+		"liveness.missed_blocks": {"501"},
+		"liveness.height":        {"1234"},
+		"slash.address":          {slashed},
+		"slash.power":            {"10"},
+		"slash.reason":           {"missing_signature"},
+		"slash.jailed":           {slashed},
+	}
+
+	prevote := &types.Vote{
+		Type:             tmproto.PrevoteType,
+		Height:           1235,
+		BlockID:          blockId(b1235),
+		Timestamp:        b1235.Time.Add(1200 * time.Millisecond),
+		ValidatorAddress: watched.Address,
+		ValidatorIndex:   0,
+	}
+	sign(prevote)
+	round := types.EventDataNewRound{Height: 1235, Step: "RoundStepNewRound", Proposer: types.ValidatorInfo{Address: watched.Address, Index: 0}}
+
+	for _, v := range []struct {
+		version string
+		node    string
+		block   []byte
+		vote    []byte
+	}{
+		{"v0.34", "0.34.28", unwrapTm(types.EventDataNewBlock{Block: b1234, ResultBeginBlock: abci.ResponseBeginBlock{Events: begin}, ResultEndBlock: end}), unwrapTm(types.EventDataVote{Vote: prevote})},
+		{"v0.37", "0.37.2", newBlock037(b1234, begin, end), unwrapTm(types.EventDataVote{Vote: prevote})},
+		{"v0.38", "0.38.2", newBlock038(b1234, begin, end), vote038(prevote)},
+	} {
+		write(v.version, "new_block.json", event(types.EventNewBlock, v.block, blockEvents))
+		write(v.version, "vote.json", event(types.EventVote, v.vote, map[string][]string{"tm.event": {"Vote"}}))
+		write(v.version, "new_round.json", event(types.EventNewRound, unwrapTm(round), map[string][]string{"tm.event": {"NewRound"}}))
+		write(v.version, "status.json", status(v.node, b1234))
+	}
+}
+
+func check(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
+
+func mustTm(v interface{}) []byte {
+	b, err := tmjson.Marshal(v)
+	check(err)
+	return b
+}
+
+// unwrapTm marshals a registered event type without the type and value wrapper, event() adds it for every version.
+func unwrapTm(v interface{}) []byte {
+	wrapped := &struct {
+		Value json.RawMessage `json:"value"`
+	}{}
+	check(json.Unmarshal(mustTm(v), wrapped))
+	return wrapped.Value
+}
+
+func valcons(addr types.Address) string {
+	s, err := bech32.ConvertAndEncode("cosmosvalcons", addr)
+	check(err)
+	return s
+}
+
+func sign(vote *types.Vote) {
+	p := vote.ToProto()
+	check(pvs[string(vote.ValidatorAddress)].SignVote(chainId, p))
+	vote.Signature = p.Signature
+}
+
+func blockId(b *types.Block) types.BlockID {
+	ps := b.MakePartSet(types.BlockPartSizeBytes)
+	return types.BlockID{Hash: b.Hash(), PartSetHeader: ps.Header()}
+}
+
+// commit is signed by every validator except the absent one, a few hundred milliseconds apart.
+func commit(b *types.Block, absent types.Address) *types.Commit {
+	id := blockId(b)
+	voteSet := types.NewVoteSet(chainId, b.Height, 0, tmproto.PrecommitType, valSet)
+	for i, val := range valSet.Validators {
+		if bytes.Equal(val.Address, absent) {
+			continue
+		}
+		vote := &types.Vote{
+			Type:             tmproto.PrecommitType,
+			Height:           b.Height,
+			BlockID:          id,
+			Timestamp:        b.Time.Add(time.Duration(5000+i*137) * time.Millisecond),
+			ValidatorAddress: val.Address,
+			ValidatorIndex:   int32(i),
+		}
+		sign(vote)
+		_, err := voteSet.AddVote(vote)
+		check(err)
+	}
+	return voteSet.MakeCommit()
+}
+
+func makeBlock(height int64, lastId types.BlockID, lastCommit *types.Commit, proposer types.Address) *types.Block {
+	b := types.MakeBlock(height, nil, lastCommit, nil)
+	appHash := bytes.Repeat([]byte{byte(height)}, 32)
+	b.Header.Populate(
+		tmversion.Consensus{Block: version.BlockProtocol},
+		chainId, genesis.Add(time.Duration(height-1232)*6*time.Second), lastId,
+		valSet.Hash(), valSet.Hash(), types.HashConsensusParams(*types.DefaultConsensusParams()), appHash, nil, proposer,
+	)
+	return b
+}
+
+type attribute struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	Index bool   `json:"index"`
+}
+
+type stringEvent struct {
+	Type       string      `json:"type"`
+	Attributes []attribute `json:"attributes"`
+}
+
+// stringEvents converts events to the v0.37 encoding, where attributes are strings. In v0.38 the SDK adds a mode
+// attribute with the phase the event was emitted in.
+func stringEvents(events []abci.Event, mode string) []stringEvent {
+	converted := make([]stringEvent, 0, len(events))
+	for _, e := range events {
+		se := stringEvent{Type: e.Type, Attributes: make([]attribute, 0)}
+		for _, a := range e.Attributes {
+			se.Attributes = append(se.Attributes, attribute{Key: string(a.Key), Value: string(a.Value), Index: a.Index})
+		}
+		if mode != "" {
+			se.Attributes = append(se.Attributes, attribute{Key: "mode", Value: mode})
+		}
+		converted = append(converted, se)
+	}
+	return converted
+}
+
+func newBlock037(b *types.Block, begin []abci.Event, end abci.ResponseEndBlock) []byte {
+	out, err := json.Marshal(struct {
+		Block            json.RawMessage `json:"block"`
+		ResultBeginBlock struct {
+			Events []stringEvent `json:"events"`
+		} `json:"result_begin_block"`
+		ResultEndBlock struct {
+			ValidatorUpdates      json.RawMessage `json:"validator_updates"`
+			ConsensusParamUpdates json.RawMessage `json:"consensus_param_updates"`
+			Events                []stringEvent   `json:"events"`
+		} `json:"result_end_block"`
+	}{
+		Block: mustTm(b),
+		ResultBeginBlock: struct {
+			Events []stringEvent `json:"events"`
+		}{stringEvents(begin, "")},
+		ResultEndBlock: struct {
+			ValidatorUpdates      json.RawMessage `json:"validator_updates"`
+			ConsensusParamUpdates json.RawMessage `json:"consensus_param_updates"`
+			Events                []stringEvent   `json:"events"`
+		}{mustTm(end.ValidatorUpdates), []byte("null"), stringEvents(end.Events, "")},
+	})
+	check(err)
+	return out
+}
+
+func newBlock038(b *types.Block, begin []abci.Event, end abci.ResponseEndBlock) []byte {
+	out, err := json.Marshal(struct {
+		Block               json.RawMessage `json:"block"`
+		BlockId             json.RawMessage `json:"block_id"`
+		ResultFinalizeBlock struct {
+			Events                []stringEvent   `json:"events"`
+			TxResults             []interface{}   `json:"tx_results"`
+			ValidatorUpdates      json.RawMessage `json:"validator_updates"`
+			ConsensusParamUpdates json.RawMessage `json:"consensus_param_updates"`
+			AppHash               []byte          `json:"app_hash"`
+		} `json:"result_finalize_block"`
+	}{
+		Block:   mustTm(b),
+		BlockId: mustTm(blockId(b)),
+		ResultFinalizeBlock: struct {
+			Events                []stringEvent   `json:"events"`
+			TxResults             []interface{}   `json:"tx_results"`
+			ValidatorUpdates      json.RawMessage `json:"validator_updates"`
+			ConsensusParamUpdates json.RawMessage `json:"consensus_param_updates"`
+			AppHash               []byte          `json:"app_hash"`
+		}{
+			Events:                append(stringEvents(begin, "BeginBlock"), stringEvents(end.Events, "EndBlock")...),
+			TxResults:             []interface{}{},
+			ValidatorUpdates:      mustTm(end.ValidatorUpdates),
+			ConsensusParamUpdates: []byte("null"),
+			AppHash:               bytes.Repeat([]byte{byte(b.Height + 1)}, 32),
+		},
+	})
+	check(err)
+	return out
+}
+
+// vote038 adds the vote extension fields, they are empty for prevotes.
+func vote038(vote *types.Vote) []byte {
+	v := mustTm(vote)
+	v = append(v[:len(v)-1], []byte(`,"extension":null,"extension_signature":null}`)...)
+	return []byte(`{"Vote":` + string(v) + `}`)
+}
+
+func event(eventType string, value []byte, events map[string][]string) []byte {
+	out, err := json.Marshal(struct {
+		Jsonrpc string      `json:"jsonrpc"`
+		Id      int         `json:"id"`
+		Result  interface{} `json:"result"`
+	}{"2.0", 1, struct {
+		Query string `json:"query"`
+		Data  struct {
+			Type  string          `json:"type"`
+			Value json.RawMessage `json:"value"`
+		} `json:"data"`
+		Events map[string][]string `json:"events"`
+	}{
+		Query: fmt.Sprintf("tm.event='%s'", eventType),
+		Data: struct {
+			Type  string          `json:"type"`
+			Value json.RawMessage `json:"value"`
+		}{"tendermint/event/" + eventType, value},
+		Events: events,
+	},
+	})
+	check(err)
+	return out
+}
+
+func status(nodeVersion string, b *types.Block) []byte {
+	res := &coretypes.ResultStatus{
+		NodeInfo: p2p.DefaultNodeInfo{
+			ProtocolVersion: p2p.NewProtocolVersion(version.P2PProtocol, version.BlockProtocol, 0),
+			DefaultNodeID:   p2p.PubKeyToID(ed25519.GenPrivKeyFromSecret([]byte("node")).PubKey()),
+			ListenAddr:      "tcp://0.0.0.0:26656",
+			Network:         chainId,
+			Version:         nodeVersion,
+			Channels:        []byte{0x40, 0x20, 0x21, 0x22, 0x23, 0x30, 0x38, 0x60, 0x61, 0x00},
+			Moniker:         "fixture",
+			Other:           p2p.DefaultNodeInfoOther{TxIndex: "on", RPCAddress: "tcp://0.0.0.0:26657"},
+		},
+		SyncInfo: coretypes.SyncInfo{
+			LatestBlockHash:   b.Hash(),
+			LatestAppHash:     b.AppHash,
+			LatestBlockHeight: b.Height,
+			LatestBlockTime:   b.Time,
+		},
+	}
+	out, err := json.Marshal(struct {
+		Jsonrpc string          `json:"jsonrpc"`
+		Id      int             `json:"id"`
+		Result  json.RawMessage `json:"result"`
+	}{"2.0", -1, mustTm(res)})
+	check(err)
+	return out
+}
+
+func write(version, name string, b []byte) {
+	indented := &bytes.Buffer{}
+	check(json.Indent(indented, b, "", "  "))
+	indented.WriteString("\n")
+	check(os.WriteFile(filepath.Join(dir, version, name), indented.Bytes(), 0644))
+}
@@ -0,0 +1,109 @@
+//go:build ignore
+
+// record saves the /status reply and the first NewBlock, prevote and NewRound events from a live node, replacing the
+// fixtures for its consensus version.
+//
+// Run from the repository root with: go run ./td2/testdata/events/record.go -node http://127.0.0.1:26657 -version v0.38
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gorilla/websocket"
+)
+
+const dir = "td2/testdata/events"
+
+func main() {
+	node := flag.String("node", "http://127.0.0.1:26657", "rpc url of the node to record from")
+	version := flag.String("version", "", "fixture directory to write, ie v0.34, v0.37 or v0.38")
+	flag.Parse()
+	if *version == "" {
+		log.Fatal("-version is required")
+	}
+
+	resp, err := http.Get(strings.TrimRight(*node, "/") + "/status")
+	if err != nil {
+		log.Fatal(err)
+	}
+	status, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
+	write(*version, "status.json", status)
+
+	u, err := url.Parse(*node)
+	if err != nil {
+		log.Fatal(err)
+	}
+	u.Scheme = strings.Replace(u.Scheme, "http", "ws", 1)
+	u.Path = strings.TrimRight(u.Path, "/") + "/websocket"
+	conn, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer conn.Close()
+	for _, q := range []string{`tm.event='NewBlock'`, `tm.event='Vote'`, `tm.event='NewRound'`} {
+		sub := fmt.Sprintf(`{"jsonrpc":"2.0","method":"subscribe","id":1,"params":{"query":"%s"}}`, q)
+		if err = conn.WriteMessage(websocket.TextMessage, []byte(sub)); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	names := map[string]string{
+		`tendermint/event/NewBlock`: "new_block.json",
+		`tendermint/event/Vote`:     "vote.json",
+		`tendermint/event/NewRound`: "new_round.json",
+	}
+	for len(names) > 0 {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			log.Fatal(err)
+		}
+		reply := &struct {
+			Result struct {
+				Data struct {
+					Type  string `json:"type"`
+					Value struct {
+						Vote struct {
+							Type int `json:"type"`
+						} `json:"Vote"`
+					} `json:"value"`
+				} `json:"data"`
+			} `json:"result"`
+		}{}
+		if err = json.Unmarshal(msg, reply); err != nil {
+			log.Fatal(err)
+		}
+		name, ok := names[reply.Result.Data.Type]
+		// the tests expect a prevote
+		if !ok || (name == "vote.json" && reply.Result.Data.Value.Vote.Type != 1) {
+			continue
+		}
+		write(*version, name, msg)
+		delete(names, reply.Result.Data.Type)
+	}
+}
+
+func write(version, name string, b []byte) {
+	out := &bytes.Buffer{}
+	if err := json.Indent(out, b, "", "  "); err != nil {
+		log.Fatal(err)
+	}
+	out.WriteByte('\n')
+	if err := os.WriteFile(filepath.Join(dir, version, name), out.Bytes(), 0644); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("wrote", version, name)
+}
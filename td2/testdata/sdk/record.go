@@ -0,0 +1,78 @@
+//go:build ignore
+
+// record saves the staking validator, slashing signing info and slashing params abci_query responses from a live
+// node, replacing the fixtures in a version's directory.
+//
+// Run from the repository root with:
+//
+//	go run ./td2/testdata/sdk/record.go -node http://127.0.0.1:26657 -version v0.50 \
+//	    -valoper cosmosvaloper1... -valcons cosmosvalcons1...
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const dir = "td2/testdata/sdk"
+
+func main() {
+	node := flag.String("node", "http://127.0.0.1:26657", "rpc url of the node to record from")
+	version := flag.String("version", "", "fixture directory to write, ie v0.47")
+	valoper := flag.String("valoper", "", "validator operator address")
+	valcons := flag.String("valcons", "", "validator consensus address")
+	flag.Parse()
+	if *version == "" || *valoper == "" || *valcons == "" {
+		log.Fatal("-version, -valoper and -valcons are required")
+	}
+	if err := os.MkdirAll(filepath.Join(dir, *version), 0755); err != nil {
+		log.Fatal(err)
+	}
+
+	// each request has the address in field 1, the params request is empty
+	write(*version, "validator.json", query(*node, "/cosmos.staking.v1beta1.Query/Validator", request(*valoper)))
+	write(*version, "signing_info.json", query(*node, "/cosmos.slashing.v1beta1.Query/SigningInfo", request(*valcons)))
+	write(*version, "slashing_params.json", query(*node, "/cosmos.slashing.v1beta1.Query/Params", nil))
+}
+
+func request(address string) []byte {
+	return protowire.AppendString(protowire.AppendTag(nil, 1, protowire.BytesType), address)
+}
+
+func query(node, path string, data []byte) []byte {
+	u := fmt.Sprintf("%s/abci_query?path=%s&data=0x%s", strings.TrimRight(node, "/"), url.QueryEscape(`"`+path+`"`), hex.EncodeToString(data))
+	resp, err := http.Get(u)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return b
+}
+
+func write(version, name string, b []byte) {
+	out := &bytes.Buffer{}
+	if err := json.Indent(out, b, "", "  "); err != nil {
+		log.Fatal(err)
+	}
+	out.WriteByte('\n')
+	if err := os.WriteFile(filepath.Join(dir, version, name), out.Bytes(), 0644); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("wrote", version, name)
+}
@@ -0,0 +1,290 @@
+package tenderduty
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	rpchttp "github.com/tendermint/tendermint/rpc/client/http"
+	jsonrpc "github.com/tendermint/tendermint/rpc/jsonrpc/client"
+)
+
+// TLSConfig has optional TLS settings for connecting to a node, for self-signed certificates or mTLS.
+type TLSConfig struct {
+	// CaFile is a PEM bundle of certificate authorities used to verify the node's certificate instead of the system roots
+	CaFile string `yaml:"ca_file"`
+	// InsecureSkipVerify disables verifying the node's certificate. Not recommended, use ca_file instead.
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify"`
+	// CertFile and KeyFile are a PEM encoded client certificate and key, for nodes that require mTLS
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+}
+
+// config loads the certificates, returning nil if no settings are used.
+func (t TLSConfig) config() (*tls.Config, error) {
+	if t.CaFile == "" && !t.InsecureSkipVerify && t.CertFile == "" && t.KeyFile == "" {
+		return nil, nil
+	}
+	conf := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: t.InsecureSkipVerify, //nolint:gosec // explicitly configured by the user
+	}
+	if t.CaFile != "" {
+		pem, err := os.ReadFile(t.CaFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read ca_file: %v", err)
+		}
+		conf.RootCAs = x509.NewCertPool()
+		if !conf.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("ca_file %s does not contain any PEM certificates", t.CaFile)
+		}
+	}
+	if t.CertFile != "" || t.KeyFile != "" {
+		if t.CertFile == "" || t.KeyFile == "" {
+			return nil, errors.New("both cert_file and key_file are needed for a client certificate")
+		}
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not load client certificate: %v", err)
+		}
+		conf.Certificates = []tls.Certificate{cert}
+	}
+	return conf, nil
+}
+
+// AuthConfig has optional credentials for nodes behind an authenticating proxy. They are sent with every RPC and
+// websocket request to the node.
+type AuthConfig struct {
+	// Username and Password are used for HTTP basic auth
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	// BearerToken is sent in an "Authorization: Bearer" header
+	BearerToken string `yaml:"bearer_token"`
+	// Headers are extra headers added to each request, such as an API key
+	Headers map[string]string `yaml:"headers"`
+}
+
+// header returns the headers to add to requests, nil if no settings are used.
+func (a AuthConfig) header() http.Header {
+	if a.Username == "" && a.Password == "" && a.BearerToken == "" && len(a.Headers) == 0 {
+		return nil
+	}
+	h := make(http.Header)
+	for k, v := range a.Headers {
+		h.Set(k, v)
+	}
+	if a.BearerToken != "" {
+		h.Set("Authorization", "Bearer "+a.BearerToken)
+	}
+	if a.Username != "" || a.Password != "" {
+		req := &http.Request{Header: make(http.Header)}
+		req.SetBasicAuth(a.Username, a.Password)
+		h.Set("Authorization", req.Header.Get("Authorization"))
+	}
+	return h
+}
+
+// secrets returns the values that should never be shown in logs or on the dashboard.
+func (a AuthConfig) secrets() []string {
+	s := []string{a.Password, a.BearerToken}
+	for _, v := range a.Headers {
+		s = append(s, v)
+	}
+	return s
+}
+
+// setAuth moves any credentials in the node's URL into its auth settings, the URL is used in logs and alerts.
+func (node *NodeConfig) setAuth() {
+	u, err := url.Parse(node.Url)
+	if err != nil || u.User == nil {
+		return
+	}
+	if node.Auth.Username == "" && node.Auth.Password == "" {
+		node.Auth.Username = u.User.Username()
+		node.Auth.Password, _ = u.User.Password()
+	}
+	u.User = nil
+	node.Url = u.String()
+}
+
+// minSecretLength avoids redacting short header values, which would mangle unrelated parts of a message.
+const minSecretLength = 4
+
+var (
+	secretsMux sync.RWMutex
+	secrets    []string
+)
+
+// addSecrets registers values to be removed by redact.
+func addSecrets(values ...string) {
+	secretsMux.Lock()
+	defer secretsMux.Unlock()
+	for _, v := range values {
+		if len(v) >= minSecretLength {
+			secrets = append(secrets, v)
+		}
+	}
+}
+
+// redact replaces any registered secrets in a message.
+func redact(msg string) string {
+	secretsMux.RLock()
+	defer secretsMux.RUnlock()
+	for _, s := range secrets {
+		msg = strings.ReplaceAll(msg, s, "-redacted-")
+	}
+	return msg
+}
+
+// authTransport adds a node's auth headers to each request.
+type authTransport struct {
+	base   http.RoundTripper
+	header http.Header
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for k, v := range t.header {
+		req.Header[k] = v
+	}
+	return t.base.RoundTrip(req)
+}
+
+// parseProxy checks a proxy setting, "none" or an empty string return nil. Only the schemes supported by both the
+// RPC client and the websocket are allowed.
+func parseProxy(setting string) (*url.URL, error) {
+	if setting == "" || setting == "none" {
+		return nil, nil
+	}
+	u, err := url.Parse(setting)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse proxy: %v", err)
+	}
+	switch u.Scheme {
+	case "http", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("proxy scheme %q is unknown, valid choices are http, socks5, and socks5h", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("proxy %s has no host", setting)
+	}
+	if pass, ok := u.User.Password(); ok {
+		addSecrets(pass)
+	}
+	return u, nil
+}
+
+// setProxy picks the node's proxy, falling back to the global setting unless the node's is "none". A UDS can't be
+// proxied so only uses one if it is set explicitly, which is an error.
+func (node *NodeConfig) setProxy(global *url.URL) (err error) {
+	if node.proxy, err = parseProxy(node.Proxy); err != nil {
+		return err
+	}
+	unix := strings.HasPrefix(node.Url, "unix://")
+	if node.proxy != nil && unix {
+		return errors.New("a proxy can't be used with a unix socket")
+	}
+	if node.Proxy == "" && !unix {
+		node.proxy = global
+	}
+	return nil
+}
+
+// globalProxy returns the proxy used for public endpoints and the chain registry, or nil.
+func globalProxy() *url.URL {
+	if td == nil {
+		return nil
+	}
+	return td.proxy
+}
+
+// proxyClient is an http.Client for requests that aren't to a configured node, it uses the global proxy if set.
+func proxyClient() *http.Client {
+	p := globalProxy()
+	if p == nil {
+		return http.DefaultClient
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyURL(p)
+	return &http.Client{Transport: transport}
+}
+
+// node returns the settings for a node by its URL, or nil if it isn't a configured node, ie a public endpoint.
+func (cc *ChainConfig) node(u string) *NodeConfig {
+	for _, node := range cc.Nodes {
+		if node.Url == u {
+			return node
+		}
+	}
+	return nil
+}
+
+// httpClient returns the http.Client used for a node's RPC requests. unix:// URLs are supported by the dialer.
+func (node *NodeConfig) httpClient() (*http.Client, error) {
+	client, err := jsonrpc.DefaultHTTPClient(node.Url)
+	if err != nil {
+		return nil, err
+	}
+	transport := client.Transport.(*http.Transport)
+	if node.tlsConfig != nil {
+		transport.TLSClientConfig = node.tlsConfig
+	}
+	if !strings.HasPrefix(node.Url, "unix://") {
+		// the tendermint dialer always connects to the URL's host, without the scheme's default port or a proxy
+		transport.Dial = nil
+	}
+	if node.proxy != nil {
+		transport.Proxy = http.ProxyURL(node.proxy)
+	}
+	if header := node.Auth.header(); header != nil {
+		client.Transport = &authTransport{base: client.Transport, header: header}
+	}
+	return client, nil
+}
+
+// newClient creates an RPC client for a URL, using the node's settings if it is one of the chain's nodes.
+func (cc *ChainConfig) newClient(u string) (*rpchttp.HTTP, error) {
+	node := cc.node(u)
+	if node == nil {
+		node = &NodeConfig{Url: u, proxy: globalProxy()}
+	}
+	client, err := node.httpClient()
+	if err != nil {
+		return nil, err
+	}
+	return rpchttp.NewWithClient(u, "/websocket", client)
+}
+
+// wsDialer returns the dialer for the websocket to a URL, with the node's TLS and proxy settings.
+func (cc *ChainConfig) wsDialer(u string) *websocket.Dialer {
+	dialer := *websocket.DefaultDialer
+	node := cc.node(u)
+	if node == nil {
+		node = &NodeConfig{Url: u, proxy: globalProxy()}
+	}
+	dialer.TLSClientConfig = node.tlsConfig
+	if node.proxy != nil {
+		p := *node.proxy
+		// the websocket library only knows socks5, which already resolves names on the proxy.
+		if p.Scheme == "socks5h" {
+			p.Scheme = "socks5"
+		}
+		dialer.Proxy = http.ProxyURL(&p)
+	}
+	return &dialer
+}
+
+// wsHeader returns the headers for the websocket to a URL, with the node's credentials.
+func (cc *ChainConfig) wsHeader(u string) http.Header {
+	if node := cc.node(u); node != nil {
+		return node.Auth.header()
+	}
+	return nil
+}
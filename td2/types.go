@@ -2,6 +2,7 @@ package tenderduty
 
 import (
 	"context"
+	"crypto/tls"
 	_ "embed"
 	"encoding/json"
 	"errors"
@@ -12,9 +13,11 @@ import (
 	"net/url"
 	"os"
 	"path"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	dash "github.com/blockpane/tenderduty/v2/td2/dashboard"
@@ -66,6 +69,11 @@ type Config struct {
 	Slack SlackConfig `yaml:"slack"`
 	// Healthcheck information
 	Healthcheck HealthcheckConfig `yaml:"healthcheck"`
+	// Proxy is an optional http:// or socks5:// proxy used for all nodes, public endpoints, and the chain registry
+	Proxy string `yaml:"proxy"`
+	proxy *url.URL
+	// cacheDir is where files other than the state file are saved, it is the state file's directory
+	cacheDir string
 
 	chainsMux sync.RWMutex // prevents concurrent map access for Chains
 	// Chains has settings for each validator to monitor. The map's name does not need to match the chain-id.
@@ -75,25 +83,39 @@ type Config struct {
 // savedState is dumped to a JSON file at exit time, and is loaded at start. If successful it will prevent
 // duplicate alerts, and will show old blocks in the dashboard.
 type savedState struct {
-	Alarms    *alarmCache                     `json:"alarms"`
-	Blocks    map[string][]int                `json:"blocks"`
-	NodesDown map[string]map[string]time.Time `json:"nodes_down"`
+	Alarms     *alarmCache                     `json:"alarms"`
+	Blocks     map[string][]int                `json:"blocks"`
+	Historical map[string][]bool               `json:"historical"`
+	NodesDown  map[string]map[string]time.Time `json:"nodes_down"`
 }
 
 // ChainConfig represents a validator to be monitored on a chain, it is somewhat of a misnomer since multiple
 // validators can be monitored on a single chain.
 type ChainConfig struct {
 	name           string
-	wsclient       *TmConn       // custom websocket client to work around wss:// bugs in tendermint
-	client         *rpchttp.HTTP // legit tendermint client
-	noNodes        bool          // tracks if all nodes are down
-	valInfo        *ValInfo      // recent validator state, only refreshed every few minutes
-	lastValInfo    *ValInfo      // use for detecting newly-jailed/tombstone
+	primary        *ChainConfig   // if set, this validator shares the primary's rpc client and websocket
+	followers      []*ChainConfig // additional validators monitored using this chain's rpc client and websocket
+	wsclients      []*TmConn      // custom websocket clients to work around wss:// bugs in tendermint
+	client         *rpchttp.HTTP  // legit tendermint client
+	noNodes        bool           // tracks if all nodes are down
+	valInfo        *ValInfo       // recent validator state, only refreshed every few minutes
+	lastValInfo    *ValInfo       // use for detecting newly-jailed/tombstone
 	blocksResults  []int
+	historical     []bool       // which of the blocksResults were loaded at startup, rather than seen live
+	resultsMux     sync.RWMutex // guards replacing blocksResults and historical, they are read by other goroutines
 	lastError      string
 	lastBlockTime  time.Time
 	lastBlockAlarm bool
 	lastBlockNum   int64
+	sdkVersion     string         // cosmos-sdk version reported by the application, only informational
+	sdkUnknown     bool           // the cosmos-sdk version lookup failed, and the failure was logged
+	grpcConns      *grpcPool      // connections to GrpcNodes, nil if none are configured
+	light          *lightVerifier // nil unless light client verification is enabled
+	public         *publicPool    // nil unless public fallback is enabled
+	restart        chan struct{}  // signals WsRun to reconnect, when the nodes should be chosen again
+	activeNode     atomic.Value   // the url WsRun is using, a string
+	checkingNodes  int32          // set while checkConsistency is running
+	blockTimes     blockTimes
 	activeAlerts   int
 
 	statTotalSigns      float64
@@ -102,14 +124,28 @@ type ChainConfig struct {
 	statPrevoteMiss     float64
 	statPrecommitMiss   float64
 	statConsecutiveMiss float64
+	statProposalMiss    float64
+	proposalMissed      int64 // the last height where the validator missed its turn to propose
+
+	oracle          *oracleState       // most recent oracle miss counter, only used if the oracle monitor is enabled
+	delegations     *delegationState   // most recent snapshot of the validator's delegations
+	unbonding       map[string]float64 // unbonding balances by delegator at the time of the last snapshot
+	delegationAlert string             // set when bonded tokens have moved more than the configured threshold
+
+	lastBalanceCheck time.Time
+	lowBalances      map[string]bool // alarm message -> if the balance is low, replaced on each refresh
 
 	// ChainId is used to ensure any endpoints contacted claim to be on the correct chain. This is a weak verification,
-	// no light client validation is performed, so caution is advised when using public endpoints.
+	// unless light_client is enabled no validation is performed, so caution is advised when using public endpoints.
 	ChainId string `yaml:"chain_id"`
 	// ValAddress is the validator operator address to be monitored. Tenderduty v1 required the consensus address,
 	// this is no longer needed. The operator address is much easier to find in explorers etc.
 	ValAddress string `yaml:"valoper_address"`
-	// ValconsOverride allows skipping the lookup of the consensus public key and setting it directly.
+	// ValAddresses are additional validator operator addresses to monitor on the same chain. They share the RPC
+	// client and websocket subscription, but have their own stats, alarms, and dashboard rows.
+	ValAddresses []string `yaml:"valoper_addresses"`
+	// ValconsOverride allows skipping the lookup of the consensus public key and setting it directly. It is used by the
+	// consensus-only backend.
 	ValconsOverride string `yaml:"valcons_override"`
 	// ExtraInfo will be appended to the alert data. This is useful for pagerduty because multiple tenderduty instances
 	// can be pointed at pagerduty and duplicate alerts will be filtered by using a key. The first alert will win, this
@@ -117,11 +153,44 @@ type ChainConfig struct {
 	ExtraInfo string `yaml:"extra_info"` // FIXME not used yet!
 	// Alerts defines the types of alerts to send for this chain.
 	Alerts AlertConfig `yaml:"alerts"`
+	// Accounts are operator wallets (price-feeder, relayer, etc.) that will alert if their balance falls too low
+	Accounts []*AccountConfig `yaml:"accounts"`
+	// Oracle configures monitoring of price-feeder votes for chains with an oracle module
+	Oracle OracleConfig `yaml:"oracle"`
+	// Backend selects how validator information is queried: "cosmos-sdk" (the default) uses the staking and
+	// slashing modules, "consensus-only" only uses the tendermint RPC for chains without x/staking.
+	Backend string `yaml:"backend"`
+	// Consumer enables Interchain Security consumer chain mode, where the validator is found on the provider chain
+	Consumer ConsumerConfig `yaml:"consumer"`
 	// PublicFallback determines if tenderduty should attempt to use public RPC endpoints in the situation that not
 	// explicitly defined RPC servers are available. Not recommended.
 	PublicFallback bool `yaml:"public_fallback"`
 	// Nodes defines what RPC servers to connect to.
 	Nodes []*NodeConfig `yaml:"nodes"`
+	// WebsocketNodes is how many nodes to subscribe to for new blocks and votes at the same time, events are merged by
+	// height so a node dropping votes or disconnecting doesn't cause false missed blocks. Defaults to 1.
+	WebsocketNodes int `yaml:"websocket_nodes"`
+	// GrpcNodes are optional gRPC endpoints used for staking, slashing, bank, and other module queries instead of
+	// ABCI queries. The RPC nodes are still required for the websocket. Queries fall back to ABCI if gRPC fails.
+	GrpcNodes []string `yaml:"grpc_nodes"`
+	// LightClient enables verifying headers and commits from a trusted header, nodes serving blocks that fail
+	// verification are treated as down.
+	LightClient LightClientConfig `yaml:"light_client"`
+}
+
+// mkAccountUpdate returns the info needed by prometheus for an account balance gauge.
+func (cc *ChainConfig) mkAccountUpdate(address, denom string, v float64) *promUpdate {
+	u := cc.mkUpdate(metricAccountBalance, v, "")
+	u.address = address
+	u.denom = denom
+	return u
+}
+
+// mkCheckUpdate returns the info needed by prometheus for a node health check gauge.
+func (cc *ChainConfig) mkCheckUpdate(node, check string, v float64) *promUpdate {
+	u := cc.mkUpdate(metricNodeCheckFailed, v, node)
+	u.check = check
+	return u
 }
 
 // mkUpdate returns the info needed by prometheus for a gauge.
@@ -157,6 +226,45 @@ type AlertConfig struct {
 	// PercentageAlerts is whether to alert on percentage based misses
 	PercentageAlerts bool `yaml:"percentage_enabled"`
 
+	// ProposalAlerts enables alerting when the validator was the proposer for a round but the block was not proposed by it
+	ProposalAlerts bool `yaml:"proposal_missed_enabled"`
+	// ProposalPriority is a tag for pagerduty to route on priority
+	ProposalPriority string `yaml:"proposal_missed_priority"`
+
+	// BlockTimeAlerts enables alerting when the average block time degrades
+	BlockTimeAlerts bool `yaml:"block_time_enabled"`
+	// BlockTimeMultiple is how many times slower than the baseline the average block time must be to alert, defaults to 2
+	BlockTimeMultiple float64 `yaml:"block_time_multiple"`
+	// BlockTimeBaseline is the expected block time in seconds, if 0 it is learned from observed blocks
+	BlockTimeBaseline float64 `yaml:"block_time_baseline_seconds"`
+	// BlockTimePriority is a tag for pagerduty to route on priority
+	BlockTimePriority string `yaml:"block_time_priority"`
+
+	// BalancePriority is a tag for pagerduty to route on priority for low account balances
+	BalancePriority string `yaml:"balance_priority"`
+
+	// VersionAlerts enables alerting when a chain's nodes are running different or unexpected versions
+	VersionAlerts bool `yaml:"version_skew_enabled"`
+	// VersionPriority is a tag for pagerduty to route on priority
+	VersionPriority string `yaml:"version_skew_priority"`
+	// ConsistencyAlerts compares the block hashes and missed block counters reported by the nodes, alerting when one
+	// disagrees with the majority. The node isn't used for monitoring until it agrees again.
+	ConsistencyAlerts bool `yaml:"consistency_enabled"`
+	// ConsistencyPriority is a tag for pagerduty to route on priority
+	ConsistencyPriority string `yaml:"consistency_priority"`
+	// ConsensusKeyPriority is a tag for pagerduty to route on priority when a validator node has the wrong key or no
+	// voting power, defaults to critical
+	ConsensusKeyPriority string `yaml:"consensus_key_priority"`
+
+	// DelegationAlerts enables alerting on large changes in the validator's bonded tokens
+	DelegationAlerts bool `yaml:"delegation_enabled"`
+	// DelegationChange is the absolute change in bonded tokens (in the base denom) between refreshes that triggers an alert
+	DelegationChange float64 `yaml:"delegation_change"`
+	// DelegationPercent is the change in bonded tokens as a percentage between refreshes that triggers an alert
+	DelegationPercent float64 `yaml:"delegation_percentage"`
+	// DelegationPriority is a tag for pagerduty to route on priority
+	DelegationPriority string `yaml:"delegation_priority"`
+
 	// AlertIfInactive decides if tenderduty send an alert if the validator is not in the active set?
 	AlertIfInactive bool `yaml:"alert_if_inactive"`
 	// AlertIfNoServers: should an alert be sent if no servers are reachable?
@@ -185,14 +293,31 @@ type AlertConfig struct {
 
 // NodeConfig holds the basic information for a node to connect to.
 type NodeConfig struct {
-	Url         string `yaml:"url"`
-	AlertIfDown bool   `yaml:"alert_if_down"`
-
-	down      bool
-	wasDown   bool
-	syncing   bool
-	lastMsg   string
-	downSince time.Time
+	Url         string       `yaml:"url"`
+	AlertIfDown bool         `yaml:"alert_if_down"`
+	Health      HealthConfig `yaml:"health"`
+	// ValidatorNode marks this node as the one signing for the validator, its consensus key will be checked.
+	ValidatorNode bool `yaml:"validator_node"`
+	// TLS has optional settings for self-signed certificates and client certificates
+	TLS TLSConfig `yaml:"tls"`
+	// Auth has optional credentials: basic auth, a bearer token, or extra headers
+	Auth AuthConfig `yaml:"auth"`
+	// Proxy overrides the global proxy for this node, "none" connects directly
+	Proxy string `yaml:"proxy"`
+
+	down       bool
+	wasDown    bool
+	version    string // tendermint version from /status
+	appVersion string // application version from /abci_info
+	keyProblem string // set if a validator node is not using the expected consensus key
+	// inconsistent is set when the node disagrees with the majority of nodes, it isn't used until it agrees again
+	inconsistent string
+	syncing      bool
+	lastMsg      string
+	downSince    time.Time
+	tlsConfig    *tls.Config // loaded from TLS, nil uses the defaults
+	proxy        *url.URL    // from Proxy or the global setting, nil connects directly
+	score        nodeScore   // used to pick the best node for queries and the websocket
 }
 
 // PDConfig is the information required to send alerts to PagerDuty
@@ -244,6 +369,11 @@ func validateConfig(c *Config) (fatal bool, problems []string) {
 		}
 	}
 
+	if c.proxy, err = parseProxy(c.Proxy); err != nil {
+		fatal = true
+		problems = append(problems, fmt.Sprintf("error: invalid proxy setting: %v", err))
+	}
+
 	if c.Pagerduty.Enabled {
 		rex := regexp.MustCompile(`[+_-]`)
 		if rex.MatchString(c.Pagerduty.ApiKey) {
@@ -258,12 +388,49 @@ func validateConfig(c *Config) (fatal bool, problems []string) {
 
 	var wantsPublic bool
 	for k, v := range c.Chains {
+		if len(v.GrpcNodes) > 0 && v.primary == nil && v.grpcConns == nil {
+			v.grpcConns = newGrpcPool(v.GrpcNodes)
+		}
+		if v.LightClient.Enabled && v.primary == nil && v.light == nil {
+			var err error
+			if v.light, err = newLightVerifier(v.ChainId, v.LightClient); err != nil {
+				fatal = true
+				problems = append(problems, fmt.Sprintf("error: %20s %v", k, err))
+			}
+		}
+		if v.primary == nil {
+			for _, node := range v.Nodes {
+				node.setAuth()
+				addSecrets(node.Auth.secrets()...)
+				var err error
+				if node.tlsConfig, err = node.TLS.config(); err != nil {
+					fatal = true
+					problems = append(problems, fmt.Sprintf("error: %20s node %s has invalid tls settings: %v", k, node.Url, err))
+				}
+				if err = node.setProxy(c.proxy); err != nil {
+					fatal = true
+					problems = append(problems, fmt.Sprintf("error: %20s node %s has an invalid proxy: %v", k, node.Url, err))
+				}
+			}
+		}
+		if v.PublicFallback && v.primary == nil && v.public == nil {
+			v.public = &publicPool{}
+		}
+		if v.WebsocketNodes < 1 {
+			v.WebsocketNodes = 1
+		}
+		if v.Alerts.ConsensusKeyPriority == "" {
+			v.Alerts.ConsensusKeyPriority = "critical"
+		}
 		if v.blocksResults == nil {
 			v.blocksResults = make([]int, showBLocks)
 			for i := range v.blocksResults {
 				v.blocksResults[i] = -1
 			}
 		}
+		if len(v.historical) != len(v.blocksResults) {
+			v.historical = make([]bool, len(v.blocksResults))
+		}
 		if v.name == "" {
 			v.name = k
 		}
@@ -318,12 +485,47 @@ func validateConfig(c *Config) (fatal bool, problems []string) {
 			fallthrough
 		case v.Alerts.Telegram.Enabled && !c.Telegram.Enabled:
 			problems = append(problems, fmt.Sprintf("warn: %20s is configured for telegram alerts, but it is not enabled", k))
-		case !v.Alerts.ConsecutiveAlerts && !v.Alerts.PercentageAlerts && !v.Alerts.AlertIfInactive && !v.Alerts.AlertIfNoServers:
+		case !v.Alerts.ConsecutiveAlerts && !v.Alerts.PercentageAlerts && !v.Alerts.AlertIfInactive && !v.Alerts.AlertIfNoServers && !v.Alerts.DelegationAlerts && !v.Alerts.BlockTimeAlerts && !v.Alerts.ProposalAlerts && !v.Alerts.VersionAlerts && !v.Alerts.ConsistencyAlerts:
 			problems = append(problems, fmt.Sprintf("warn: %20s has no alert types configured", k))
 			fallthrough
 		case !v.Alerts.Pagerduty.Enabled && !v.Alerts.Discord.Enabled && !v.Alerts.Telegram.Enabled && !v.Alerts.Slack.Enabled:
 			problems = append(problems, fmt.Sprintf("warn: %20s has no notifications configured", k))
 		}
+		if v.Oracle.Enabled && v.Oracle.prefix() == "" {
+			problems = append(problems, fmt.Sprintf("warn: %20s has an unknown oracle module %q, and no query_prefix set", k, v.Oracle.Module))
+			v.Oracle.Enabled = false
+		}
+		if _, known := oracleModules[v.Oracle.Module]; v.Oracle.Enabled && !known && (v.Oracle.VotePeriod == 0 || v.Oracle.SlashWindow == 0) {
+			problems = append(problems, fmt.Sprintf("warn: %20s uses oracle module %q, vote_period and slash_window must be set", k, v.Oracle.Module))
+			v.Oracle.Enabled = false
+		}
+		if v.Oracle.Enabled && v.Oracle.ConsecutiveAlerts && v.Oracle.ConsecutiveMissed < 1 {
+			problems = append(problems, fmt.Sprintf("warn: %20s oracle consecutive_missed must be at least 1, using 1", k))
+			v.Oracle.ConsecutiveMissed = 1
+		}
+		if v.Consumer.Enabled && len(v.Consumer.ProviderNodes) == 0 {
+			problems = append(problems, fmt.Sprintf("warn: %20s is a consumer chain, but has no provider_nodes configured", k))
+			v.Consumer.Enabled = false
+		}
+		if err := validBackend(v.Backend); err != nil {
+			problems = append(problems, fmt.Sprintf("warn: %20s has an %v, using %s", k, err, backendCosmos))
+			v.Backend = backendCosmos
+		}
+		if v.Consumer.Enabled && v.Alerts.DelegationAlerts {
+			// delegations are on the provider chain, and are the same for every consumer
+			problems = append(problems, fmt.Sprintf("warn: %20s is a consumer chain, delegation alerts are not supported", k))
+			v.Alerts.DelegationAlerts = false
+		}
+		if v.Backend == backendConsensus && v.Alerts.DelegationAlerts {
+			problems = append(problems, fmt.Sprintf("warn: %20s uses the %s backend, delegation alerts are not supported", k, backendConsensus))
+			v.Alerts.DelegationAlerts = false
+		}
+		if v.Alerts.BlockTimeAlerts && v.Alerts.BlockTimeMultiple <= 1 {
+			v.Alerts.BlockTimeMultiple = 2
+		}
+		if v.Alerts.DelegationAlerts && v.Alerts.DelegationChange <= 0 && v.Alerts.DelegationPercent <= 0 {
+			problems = append(problems, fmt.Sprintf("warn: %20s has delegation alerts enabled, but neither delegation_change or delegation_percentage is set", k))
+		}
 		if td.EnableDash {
 			td.updateChan <- &dash.ChainStatus{
 				MsgType:      "status",
@@ -391,7 +593,7 @@ func loadChainConfig(yamlFile string) (*ChainConfig, error) {
 // loadConfig creates a new Config from a file.
 func loadConfig(yamlFile, stateFile, chainConfigDirectory string, password *string) (*Config, error) {
 
-	c := &Config{}
+	c := &Config{cacheDir: filepath.Dir(stateFile)}
 	if strings.HasPrefix(yamlFile, "http://") || strings.HasPrefix(yamlFile, "https://") {
 		if *password == "" {
 			return nil, errors.New("a password is required if loading a remote configuration")
@@ -477,6 +679,8 @@ func loadConfig(yamlFile, stateFile, chainConfigDirectory string, password *stri
 		return nil, errors.New("no chains configured")
 	}
 
+	addFollowers(c)
+
 	c.alertChan = make(chan *alertMsg)
 	c.logChan = make(chan dash.LogMessage)
 	// buffer enough to get through validateConfig()
@@ -514,6 +718,11 @@ func loadConfig(yamlFile, stateFile, chainConfigDirectory string, password *stri
 			c.Chains[k].blocksResults = v
 		}
 	}
+	for k, v := range saved.Historical {
+		if c.Chains[k] != nil {
+			c.Chains[k].historical = v
+		}
+	}
 
 	// restore alarm state to prevent duplicate alerts
 	if saved.Alarms != nil {
@@ -575,6 +784,66 @@ func loadConfig(yamlFile, stateFile, chainConfigDirectory string, password *stri
 	return c, nil
 }
 
+// addFollowers creates a ChainConfig for each additional validator listed in valoper_addresses. They are added to
+// the Chains map so that alerts, saved state and the dashboard treat them like any other validator.
+func addFollowers(c *Config) {
+	followers := make(map[string]*ChainConfig)
+	for k, v := range c.Chains {
+		if v.ValAddress == "" && len(v.ValAddresses) > 0 {
+			v.ValAddress, v.ValAddresses = v.ValAddresses[0], v.ValAddresses[1:]
+		}
+		for i, addr := range v.ValAddresses {
+			name := fmt.Sprintf("%s #%d", k, i+2)
+			if c.Chains[name] != nil {
+				l(fmt.Sprintf("not adding %s to %s, a chain named %s already exists", addr, k, name))
+				continue
+			}
+			f := &ChainConfig{
+				name:           name,
+				primary:        v,
+				ChainId:        v.ChainId,
+				ValAddress:     addr,
+				ExtraInfo:      v.ExtraInfo,
+				Alerts:         v.Alerts,
+				Oracle:         v.Oracle,
+				Backend:        v.Backend,
+				Consumer:       v.Consumer,
+				PublicFallback: v.PublicFallback,
+				LightClient:    v.LightClient,
+				Nodes:          v.Nodes,
+			}
+			v.followers = append(v.followers, f)
+			followers[name] = f
+		}
+	}
+	for k, v := range followers {
+		c.Chains[k] = v
+	}
+}
+
+// validators returns the primary validator for a chain, followed by any additional validators sharing its client.
+func (cc *ChainConfig) validators() []*ChainConfig {
+	return append([]*ChainConfig{cc}, cc.followers...)
+}
+
+// anyConspub returns true if at least one of the validators on the chain has a consensus key.
+func (cc *ChainConfig) anyConspub() bool {
+	for _, v := range cc.validators() {
+		if v.valInfo != nil && v.valInfo.Conspub != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// setClient shares the rpc client with any additional validators on the chain.
+func (cc *ChainConfig) setClient(noNodes bool) {
+	for _, f := range cc.followers {
+		f.client = cc.client
+		f.noNodes = noNodes
+	}
+}
+
 func clearStale(alarms map[string]time.Time, what string, hasPagerduty bool, hours float64) {
 	for k := range alarms {
 		if time.Since(alarms[k]).Hours() >= hours {
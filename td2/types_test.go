@@ -0,0 +1,55 @@
+package tenderduty
+
+import (
+	"testing"
+
+	rpchttp "github.com/tendermint/tendermint/rpc/client/http"
+)
+
+func TestAddFollowers(t *testing.T) {
+	primary := &ChainConfig{
+		name:            "osmosis",
+		ChainId:         "osmosis-1",
+		ValAddresses:    []string{"osmovaloper1a", "osmovaloper1b", "osmovaloper1c"},
+		ValconsOverride: "osmovalcons1a",
+		Accounts:        []*AccountConfig{{Address: "osmo1feeder", Label: "price-feeder"}},
+		Alerts:          AlertConfig{ProposalAlerts: true},
+		Nodes:           []*NodeConfig{{Url: "http://127.0.0.1:26657"}, {Url: "http://127.0.0.2:26657"}},
+	}
+	existing := &ChainConfig{name: "osmosis #3", ChainId: "other-1"}
+	c := &Config{Chains: map[string]*ChainConfig{"osmosis": primary, "osmosis #3": existing}}
+	addFollowers(c)
+
+	// the first address is the primary's, and a follower can't replace a chain with the same name
+	if primary.ValAddress != "osmovaloper1a" || len(primary.followers) != 1 || c.Chains["osmosis #3"] != existing {
+		t.Fatalf("unexpected followers for %s: %d", primary.ValAddress, len(primary.followers))
+	}
+	f := c.Chains["osmosis #2"]
+	if f == nil || f != primary.followers[0] || f.primary != primary {
+		t.Fatal("expected osmosis #2 to follow osmosis")
+	}
+	if f.name != "osmosis #2" || f.ChainId != "osmosis-1" || f.ValAddress != "osmovaloper1b" || !f.Alerts.ProposalAlerts {
+		t.Errorf("unexpected follower %s %s %s", f.name, f.ChainId, f.ValAddress)
+	}
+
+	// the nodes and client are shared, so the follower sees the primary's health and failover
+	if len(f.Nodes) != 2 || f.Nodes[0] != primary.Nodes[0] || f.Nodes[1] != primary.Nodes[1] {
+		t.Error("expected the follower to share the primary's nodes")
+	}
+	var err error
+	if primary.client, err = rpchttp.New(primary.Nodes[0].Url, "/websocket"); err != nil {
+		t.Fatal(err)
+	}
+	primary.setClient(true)
+	if f.client != primary.client || !f.noNodes {
+		t.Error("expected the follower to share the primary's client")
+	}
+	if got := primary.validators(); len(got) != 2 || got[0] != primary || got[1] != f {
+		t.Errorf("expected the primary and its follower, got %d validators", len(got))
+	}
+
+	// settings that belong to a single validator stay with the primary
+	if f.ValconsOverride != "" || f.Accounts != nil || f.ValAddresses != nil {
+		t.Errorf("unexpected settings copied to the follower: %q %v %v", f.ValconsOverride, f.Accounts, f.ValAddresses)
+	}
+}
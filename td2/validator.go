@@ -11,9 +11,8 @@ import (
 	"github.com/cosmos/cosmos-sdk/crypto/keys/ed25519"
 	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
 	"github.com/cosmos/cosmos-sdk/types/bech32"
-	slashing "github.com/cosmos/cosmos-sdk/x/slashing/types"
 	staking "github.com/cosmos/cosmos-sdk/x/staking/types"
-	rpchttp "github.com/tendermint/tendermint/rpc/client/http"
+	coretypes "github.com/tendermint/tendermint/rpc/core/types"
 )
 
 // ValInfo holds most of the stats/info used for secondary alarms. It is refreshed roughly every minute.
@@ -40,107 +39,51 @@ func (cc *ChainConfig) GetValInfo(first bool) (err error) {
 		cc.valInfo = &ValInfo{}
 	}
 
-	// Fetch info from /cosmos.staking.v1beta1.Query/Validator
-	// it's easier to ask people to provide valoper since it's readily available on
-	// explorers, so make it easy and lookup the consensus key for them.
-	cc.valInfo.Conspub, cc.valInfo.Moniker, cc.valInfo.Jailed, cc.valInfo.Bonded, err = getVal(ctx, cc.client, cc.ValAddress)
+	cc.detectSdkVersion(ctx)
+	backend := cc.backend()
+	val, err := backend.validator(ctx, cc)
 	if err != nil {
 		return
 	}
+	cc.valInfo.Conspub, cc.valInfo.Valcons, cc.valInfo.Moniker, cc.valInfo.Jailed, cc.valInfo.Bonded = val.conspub, val.valcons, val.moniker, val.jailed, val.bonded
 	if first && cc.valInfo.Bonded {
 		l(fmt.Sprintf("⚙️ found %s (%s) in validator set", cc.ValAddress, cc.valInfo.Moniker))
 	} else if first && !cc.valInfo.Bonded {
 		l(fmt.Sprintf("❌ %s (%s) is INACTIVE", cc.ValAddress, cc.valInfo.Moniker))
 	}
-
-	if strings.Contains(cc.ValAddress, "valcons") {
-		// no need to change prefix for signing info query
-		cc.valInfo.Valcons = cc.ValAddress
-	} else {
-		// need to know the prefix for when we serialize the slashing info query, this is too fragile.
-		// for now, we perform specific chain overrides based on known values because the valoper is used
-		// in so many places.
-		var prefix string
-		split := strings.Split(cc.ValAddress, "valoper")
-		if len(split) != 2 {
-			if pre, ok := altValopers.getAltPrefix(cc.ValAddress); ok {
-				cc.valInfo.Valcons, err = bech32.ConvertAndEncode(pre, cc.valInfo.Conspub[:20])
-				if err != nil {
-					return
-				}
-			} else {
-				err = errors.New("❓ could not determine bech32 prefix from valoper address: " + cc.ValAddress)
-				return
-			}
-		} else {
-			prefix = split[0] + "valcons"
-			cc.valInfo.Valcons, err = bech32.ConvertAndEncode(prefix, cc.valInfo.Conspub[:20])
-			if err != nil {
-				return
-			}
-		}
-		if first {
-			l("⚙️", cc.ValAddress[:20], "... is using consensus key:", cc.valInfo.Valcons)
+	if first {
+		switch {
+		case cc.valInfo.Valcons != "" && cc.valInfo.Valcons != cc.ValAddress:
+			l("⚙️", cc.ValAddress, "is using consensus key:", cc.valInfo.Valcons)
+		case cc.valInfo.Valcons == "":
+			l(fmt.Sprintf("⚙️ %s is using consensus address %X on %s", cc.ValAddress, cc.valInfo.Conspub, cc.ChainId))
 		}
-
 	}
 
-	// get current signing information (tombstoned, missed block count)
-	qSigning := slashing.QuerySigningInfoRequest{ConsAddress: cc.valInfo.Valcons}
-	b, err := qSigning.Marshal()
-	if err != nil {
-		return
-	}
-	resp, err := cc.client.ABCIQuery(ctx, "/cosmos.slashing.v1beta1.Query/SigningInfo", b)
-	if resp == nil || resp.Response.Value == nil {
-		err = errors.New("could not query validator slashing status, got empty response")
-		return
-	}
-	slash := &slashing.QuerySigningInfoResponse{}
-	err = slash.Unmarshal(resp.Response.Value)
+	signing, err := backend.signing(ctx, cc)
 	if err != nil {
 		return
 	}
-	cc.valInfo.Tombstoned = slash.ValSigningInfo.Tombstoned
+	cc.valInfo.Tombstoned = signing.tombstoned
 	if cc.valInfo.Tombstoned {
 		l(fmt.Sprintf("❗️☠️ %s (%s) is tombstoned 🪦❗️", cc.ValAddress, cc.valInfo.Moniker))
 	}
-	cc.valInfo.Missed = slash.ValSigningInfo.MissedBlocksCounter
+	cc.valInfo.Missed = signing.missed
 	if td.Prom {
 		td.statsChan <- cc.mkUpdate(metricWindowMissed, float64(cc.valInfo.Missed), "")
-	}
-
-	// finally get the signed blocks window
-	if cc.valInfo.Window == 0 {
-		qParams := &slashing.QueryParamsRequest{}
-		b, err = qParams.Marshal()
-		if err != nil {
-			return
-		}
-		resp, err = cc.client.ABCIQuery(ctx, "/cosmos.slashing.v1beta1.Query/Params", b)
-		if err != nil {
-			return
+		if first || signing.window != cc.valInfo.Window {
+			td.statsChan <- cc.mkUpdate(metricWindowSize, float64(signing.window), "")
 		}
-		if resp.Response.Value == nil {
-			err = errors.New("🛑 could not query slashing params, got empty response")
-			return
-		}
-		params := &slashing.QueryParamsResponse{}
-		err = params.Unmarshal(resp.Response.Value)
-		if err != nil {
-			return
-		}
-		if first && td.Prom {
-			td.statsChan <- cc.mkUpdate(metricWindowSize, float64(params.Params.SignedBlocksWindow), "")
+		if first {
 			td.statsChan <- cc.mkUpdate(metricTotalNodes, float64(len(cc.Nodes)), "")
 		}
-		cc.valInfo.Window = params.Params.SignedBlocksWindow
 	}
+	cc.valInfo.Window = signing.window
 	return
 }
 
 // getVal returns the public key, moniker, and if the validator is jailed.
-func getVal(ctx context.Context, client *rpchttp.HTTP, valoper string) (pub []byte, moniker string, jailed, bonded bool, err error) {
+func getVal(ctx context.Context, query queryFunc, valoper string) (pub []byte, moniker string, jailed, bonded bool, err error) {
 	if strings.Contains(valoper, "valcons") {
 		_, bz, err := bech32.DecodeAndConvert(valoper)
 		if err != nil {
@@ -158,34 +101,30 @@ func getVal(ctx context.Context, client *rpchttp.HTTP, valoper string) (pub []by
 	if err != nil {
 		return
 	}
-	resp, err := client.ABCIQuery(ctx, "/cosmos.staking.v1beta1.Query/Validator", b)
+	resp, err := query(ctx, "/cosmos.staking.v1beta1.Query/Validator", b)
 	if err != nil {
 		return
 	}
-	if resp.Response.Value == nil {
+	if resp == nil {
 		return nil, "", false, false, errors.New("could not find validator " + valoper)
 	}
-	val := &staking.QueryValidatorResponse{}
-	err = val.Unmarshal(resp.Response.Value)
+	val, err := decodeValidator(resp)
 	if err != nil {
-		return
-	}
-	if val.Validator.ConsensusPubkey == nil {
-		return nil, "", false, false, errors.New("got invalid consensus pubkey for " + valoper)
+		return nil, "", false, false, fmt.Errorf("could not decode validator %s: %v", valoper, err)
 	}
 
 	pubBytes := make([]byte, 0)
-	switch val.Validator.ConsensusPubkey.TypeUrl {
+	switch val.pubkeyType {
 	case "/cosmos.crypto.ed25519.PubKey":
 		pk := ed25519.PubKey{}
-		err = pk.Unmarshal(val.Validator.ConsensusPubkey.Value)
+		err = pk.Unmarshal(val.pubkey)
 		if err != nil {
 			return
 		}
 		pubBytes = pk.Address().Bytes()
 	case "/cosmos.crypto.secp256k1.PubKey":
 		pk := secp256k1.PubKey{}
-		err = pk.Unmarshal(val.Validator.ConsensusPubkey.Value)
+		err = pk.Unmarshal(val.pubkey)
 		if err != nil {
 			return
 		}
@@ -195,7 +134,31 @@ func getVal(ctx context.Context, client *rpchttp.HTTP, valoper string) (pub []by
 		return nil, "", false, false, errors.New("could not get pubkey for" + valoper)
 	}
 
-	return pubBytes, val.Validator.GetMoniker(), val.Validator.Jailed, val.Validator.Status == 3, nil
+	return pubBytes, val.moniker, val.jailed, val.bonded, nil
+}
+
+// checkConsensusKey compares the key a validator node is signing with to the consensus key of the validators being
+// monitored. This catches a backup node started with the wrong priv_validator_key before it misses blocks, or worse.
+// An empty string means the key is correct.
+func (cc *ChainConfig) checkConsensusKey(node *NodeConfig, status *coretypes.ResultStatus) string {
+	nodeAddr := status.ValidatorInfo.Address.String()
+	for _, v := range cc.validators() {
+		if v.valInfo == nil || v.valInfo.Conspub == nil {
+			continue
+		}
+		if strings.ToUpper(hex.EncodeToString(v.valInfo.Conspub)) != nodeAddr {
+			continue
+		}
+		if status.ValidatorInfo.VotingPower == 0 && v.valInfo.Bonded {
+			return fmt.Sprintf("validator node %s is using the key for %s, but reports no voting power on %s", node.Url, v.valInfo.Moniker, cc.ChainId)
+		}
+		return ""
+	}
+	if cc.valInfo == nil || cc.valInfo.Conspub == nil {
+		// can't tell until the validator info is loaded
+		return ""
+	}
+	return fmt.Sprintf("validator node %s is using the wrong consensus key on %s: %s, expected %X", node.Url, cc.ChainId, nodeAddr, cc.valInfo.Conspub)
 }
 
 func ToBytes(address string) []byte {
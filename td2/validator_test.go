@@ -0,0 +1,42 @@
+package tenderduty
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tendermint/tendermint/libs/bytes"
+	coretypes "github.com/tendermint/tendermint/rpc/core/types"
+)
+
+func TestCheckConsensusKey(t *testing.T) {
+	ours, follower, other := bytes.HexBytes{0x1d, 0x8c}, bytes.HexBytes{0x2e, 0x9d}, bytes.HexBytes{0xe8, 0x77}
+	cc := &ChainConfig{ChainId: "test-1", valInfo: &ValInfo{Moniker: "ours", Conspub: ours, Bonded: true}}
+	cc.followers = []*ChainConfig{{primary: cc, valInfo: &ValInfo{Moniker: "follower", Conspub: follower}}}
+	node := &NodeConfig{Url: "http://127.0.0.1:26657", ValidatorNode: true}
+	status := func(address bytes.HexBytes, power int64) *coretypes.ResultStatus {
+		s := &coretypes.ResultStatus{}
+		s.ValidatorInfo.Address, s.ValidatorInfo.VotingPower = address, power
+		return s
+	}
+
+	if problem := cc.checkConsensusKey(node, status(ours, 10)); problem != "" {
+		t.Errorf("expected the correct key to pass, got %q", problem)
+	}
+	if problem := cc.checkConsensusKey(node, status(other, 10)); !strings.Contains(problem, "wrong consensus key on test-1: E877, expected 1D8C") {
+		t.Errorf("expected the wrong key to be reported, got %q", problem)
+	}
+	if problem := cc.checkConsensusKey(node, status(ours, 0)); !strings.Contains(problem, "using the key for ours, but reports no voting power") {
+		t.Errorf("expected the missing voting power to be reported, got %q", problem)
+	}
+
+	// a follower's key is correct, and it's not bonded so no voting power is expected
+	if problem := cc.checkConsensusKey(node, status(follower, 0)); problem != "" {
+		t.Errorf("expected the follower's key to pass, got %q", problem)
+	}
+
+	// the key can't be checked until the validator has been looked up
+	cc.valInfo, cc.followers = &ValInfo{}, nil
+	if problem := cc.checkConsensusKey(node, status(other, 10)); problem != "" {
+		t.Errorf("expected no problem before the lookup, got %q", problem)
+	}
+}
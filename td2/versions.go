@@ -0,0 +1,49 @@
+package tenderduty
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	dash "github.com/blockpane/tenderduty/v2/td2/dashboard"
+)
+
+// nodeVersions summarizes the tendermint and application versions reported by a chain's nodes. Nodes that have not
+// responded to a health check are not included.
+func (cc *ChainConfig) nodeVersions() []dash.NodeVersion {
+	counts := make(map[dash.NodeVersion]int)
+	for _, node := range cc.Nodes {
+		if node.version == "" && node.appVersion == "" {
+			continue
+		}
+		counts[dash.NodeVersion{Tendermint: node.version, App: node.appVersion}] += 1
+	}
+	versions := make([]dash.NodeVersion, 0, len(counts))
+	for v, count := range counts {
+		v.Nodes = count
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		if versions[i].Nodes != versions[j].Nodes {
+			return versions[i].Nodes > versions[j].Nodes
+		}
+		return versions[i].App+versions[i].Tendermint < versions[j].App+versions[j].Tendermint
+	})
+	return versions
+}
+
+// versionSkew returns a description of the problem if the nodes for a chain disagree on versions. An empty string means
+// no problem was found. Nodes running an unexpected version are caught by their health checks instead.
+func (cc *ChainConfig) versionSkew() string {
+	versions := cc.nodeVersions()
+	if len(versions) < 2 {
+		return ""
+	}
+	// the message is the alarm's key, so it doesn't include node counts or depend on their order
+	running := make([]string, 0, len(versions))
+	for _, v := range versions {
+		running = append(running, fmt.Sprintf("%s/tm %s", v.App, v.Tendermint))
+	}
+	sort.Strings(running)
+	return fmt.Sprintf("version skew on %s: nodes disagree: %s", cc.ChainId, strings.Join(running, ", "))
+}
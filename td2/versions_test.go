@@ -0,0 +1,23 @@
+package tenderduty
+
+import "testing"
+
+func TestVersionSkewIgnoresCounts(t *testing.T) {
+	cc := &ChainConfig{ChainId: "test-1"}
+	for _, v := range []string{"v1.0.0", "v1.0.0", "v1.1.0"} {
+		cc.Nodes = append(cc.Nodes, &NodeConfig{version: "0.34.24", appVersion: v})
+	}
+	skew := cc.versionSkew()
+	if skew == "" {
+		t.Fatal("expected version skew")
+	}
+	// another node upgrading changes the counts and which version most nodes run, but not the alarm
+	cc.Nodes[1].appVersion = "v1.1.0"
+	if again := cc.versionSkew(); again != skew {
+		t.Errorf("expected the same alarm, got %q and %q", skew, again)
+	}
+	cc.Nodes[0].appVersion = "v1.1.0"
+	if again := cc.versionSkew(); again != "" {
+		t.Errorf("expected no skew, got %q", again)
+	}
+}
@@ -10,15 +10,19 @@ import (
 	"github.com/gorilla/websocket"
 	pbtypes "github.com/tendermint/tendermint/proto/tendermint/types"
 	"log"
+	"net"
+	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
 const (
 	QueryNewBlock string = `tm.event='NewBlock'`
 	QueryVote     string = `tm.event='Vote'`
+	QueryNewRound string = `tm.event='NewRound'`
 )
 
 // StatusType represents the various possible end states. Prevote and Precommit are special cases, where the node
@@ -40,6 +44,7 @@ type StatusUpdate struct {
 	Height int64
 	Status StatusType
 	Final  bool
+	Time   time.Time // header time, only set on final updates
 }
 
 // WsReply is a trimmed down version of the JSON sent from a tendermint websocket subscription.
@@ -76,7 +81,7 @@ func (cc *ChainConfig) WsRun() {
 	started := time.Now()
 	for {
 		// wait until our RPC client is connected and running. We will use the same URL for the websocket
-		if cc.client == nil || cc.valInfo == nil || cc.valInfo.Conspub == nil {
+		if cc.client == nil || !cc.anyConspub() {
 			if started.Before(time.Now().Add(-2 * time.Minute)) {
 				l(cc.name, "websocket client timed out waiting for a working rpc endpoint, restarting")
 				return
@@ -88,33 +93,213 @@ func (cc *ChainConfig) WsRun() {
 		break
 	}
 
-	cc.wsclient, err = NewClient(cc.client.Remote(), true)
+	// a restart requested before this point is already handled, the best node is used below.
+	select {
+	case <-cc.restart:
+	default:
+	}
+
+	// the event encoding depends on the consensus version the node is running.
+	dec := eventDecoders[tendermint034]
+	sctx, scancel := context.WithTimeout(ctx, 10*time.Second)
+	status, err := cc.client.Status(sctx)
+	scancel()
 	if err != nil {
-		l(err)
-		cancel()
+		l(fmt.Sprintf("⚠️ %-12s could not get node version, using %s event decoders: %v", cc.ChainId, dec.version, err))
+	} else if dec, err = newEventDecoder(status.NodeInfo.Version); err != nil {
+		l(fmt.Sprintf("⚠️ %-12s %v, using %s event decoders", cc.ChainId, err, dec.version))
+	}
+
+	// the first websocket uses the rpc client's node, redundant subscriptions use other healthy nodes. Events from
+	// every websocket are merged by height, so a node dropping votes or disconnecting doesn't cause false misses.
+	urls := append([]string{cc.client.Remote()}, cc.redundantNodes(dec.version, cc.WebsocketNodes-1)...)
+	conns := make([]*TmConn, 0, len(urls))
+	for _, u := range urls {
+		conn, e := NewClient(u, true, cc.wsDialer(u), cc.wsHeader(u))
+		if e != nil {
+			l(e)
+			continue
+		}
+		if e = conn.SetCompressionLevel(3); e != nil {
+			log.Println(e)
+		}
+		conns = append(conns, conn)
+	}
+	if len(conns) == 0 {
 		return
 	}
-	defer cc.wsclient.Close()
-	err = cc.wsclient.SetCompressionLevel(3)
-	if err != nil {
-		log.Println(err)
+	cc.wsclients = conns
+	cc.activeNode.Store(cc.client.Remote())
+	defer func() {
+		for _, conn := range conns {
+			_ = conn.Close()
+		}
+		cc.wsclients = nil
+		cc.activeNode.Store("")
+	}()
+
+	// each validator monitored on this chain gets its own set of handlers, they all share the same subscriptions.
+	handlers := make([]*eventChans, 0)
+	for _, v := range cc.validators() {
+		if v.valInfo == nil || v.valInfo.Conspub == nil {
+			l(fmt.Sprintf("⚠️ %-12s no consensus key for %s, not watching", v.ChainId, v.ValAddress))
+			continue
+		}
+		handlers = append(handlers, v.startHandlers(ctx, cancel, dec))
+	}
+
+	// blocks from every websocket go through one sequencer, which drops duplicates and backfills gaps once for all
+	// the validators. The queue is buffered so that the websockets aren't blocked while it is backfilling.
+	blocks := make(chan *WsReply, blockQueue)
+	go cc.sequenceBlocks(ctx, dec, blocks, handlers)
+
+	// now that channel consumers are up, create our subscriptions and route data. Monitoring restarts once every
+	// websocket has closed.
+	open := int32(len(conns))
+	for _, conn := range conns {
+		go func(conn *TmConn) {
+			// with the light client enabled, each websocket's blocks are verified before they are used.
+			verified := blocks
+			if cc.lightClient() != nil {
+				verified = make(chan *WsReply, blockQueue)
+				go cc.verifyBlocks(ctx, conn, verified, blocks)
+			}
+			defer func() {
+				_ = conn.Close()
+				if atomic.AddInt32(&open, -1) == 0 {
+					cancel()
+				}
+			}()
+			for {
+				_, msg, e := conn.ReadMessage()
+				if e != nil {
+					if ctx.Err() == nil {
+						l(fmt.Sprintf("⚠️ %-12s websocket %s closed: %v", cc.ChainId, conn.remote, e))
+					}
+					return
+				}
+				reply := &WsReply{}
+				e = json.Unmarshal(msg, reply)
+				if e != nil {
+					continue
+				}
+				if reply.Type() == `tendermint/event/NewBlock` {
+					select {
+					case verified <- reply:
+					default:
+						// the queue is full, this height is backfilled when the next block arrives.
+					}
+					continue
+				}
+				for _, h := range handlers {
+					var ch chan *WsReply
+					switch reply.Type() {
+					case `tendermint/event/Vote`:
+						ch = h.votes
+					case `tendermint/event/NewRound`:
+						ch = h.rounds
+					default:
+						// fmt.Println("unknown response", reply.Type())
+						continue
+					}
+					select {
+					case ch <- reply:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}(conn)
+	}
+
+subscriptions:
+	for _, conn := range conns {
+		for _, subscribe := range []string{QueryNewBlock, QueryVote, QueryNewRound} {
+			q := fmt.Sprintf(`{"jsonrpc":"2.0","method":"subscribe","id":1,"params":{"query":"%s"}}`, subscribe)
+			if err = conn.WriteMessage(websocket.TextMessage, []byte(q)); err != nil {
+				l(err)
+				_ = conn.Close()
+				continue subscriptions
+			}
+		}
+		l(fmt.Sprintf("⚙️ %-12s watching for NewBlock, Vote and NewRound events via %s (%s)", cc.ChainId, conn.remote, dec.version))
 	}
+	for {
+		select {
+		case <-cc.client.Quit():
+			cancel()
+		case <-cc.restart:
+			l(fmt.Sprintf("🔀 %-12s restarting monitoring with a better node", cc.ChainId))
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// requestRestart signals WsRun to reconnect, choosing the nodes again. It doesn't block if a restart is already pending.
+func (cc *ChainConfig) requestRestart() {
+	select {
+	case cc.restart <- struct{}{}:
+	default:
+	}
+}
+
+// eventChans are used to route websocket events to the handlers for a single validator.
+type eventChans struct {
+	votes   chan *WsReply
+	rounds  chan *WsReply
+	blocks  chan *rawBlock    // decoded and in order, from sequenceBlocks
+	results chan StatusUpdate // for statuses of backfilled blocks
+	address string            // the validator's consensus address, hex encoded
+}
 
+// startHandlers starts the event handlers for a validator, and the goroutine that processes their results.
+func (cc *ChainConfig) startHandlers(ctx context.Context, cancel context.CancelFunc, dec *eventDecoder) *eventChans {
 	// This go func processes the results returned by the listeners. It has most of the logic on where data is sent,
 	// like dashboards or prometheus.
 	resultChan := make(chan StatusUpdate)
+	// proposerChan gets the heights where our validator was the proposer for a round
+	proposerChan := make(chan *roundProposer)
+	address := strings.ToUpper(hex.EncodeToString(cc.valInfo.Conspub))
 	go func() {
-		var signState StatusType = -1
+		// updates can arrive from more than one websocket, the best status seen for each height is used, and
+		// anything for a height that has already been recorded is a duplicate.
+		signStates := make(map[int64]StatusType)
+		finalized := cc.lastBlockNum
+		expectedProposer := make(map[int64]int32)
 		for {
 			select {
+			case p := <-proposerChan:
+				if _, ok := expectedProposer[p.height]; !ok {
+					expectedProposer[p.height] = p.round
+				}
+
 			case update := <-resultChan:
+				if update.Height <= finalized {
+					continue
+				}
 				if update.Final && update.Height%20 == 0 {
 					l(fmt.Sprintf("🧊 %-12s block %d", cc.ChainId, update.Height))
 				}
-				if update.Status > signState && cc.valInfo.Bonded {
-					signState = update.Status
+				if s, ok := signStates[update.Height]; (!ok || update.Status > s) && cc.valInfo.Bonded {
+					signStates[update.Height] = update.Status
 				}
 				if update.Final {
+					signState, ok := signStates[update.Height]
+					if !ok {
+						signState = -1
+					}
+					finalized = update.Height
+					for h := range signStates {
+						if h <= finalized {
+							delete(signStates, h)
+						}
+					}
+					if interval, ok := cc.blockTimes.add(update.Height, update.Time, cc.Alerts.BlockTimeBaseline, cc.Alerts.BlockTimeMultiple); ok && td.Prom {
+						td.statsChan <- cc.mkUpdate(metricBlockInterval, interval, "")
+						td.statsChan <- cc.mkUpdate(metricAverageBlockTime, cc.blockTimes.average, "")
+					}
 					cc.lastBlockNum = update.Height
 					if td.Prom {
 						td.statsChan <- cc.mkUpdate(metricLastBlockSeconds, time.Since(cc.lastBlockTime).Seconds(), "")
@@ -122,13 +307,29 @@ func (cc *ChainConfig) WsRun() {
 					cc.lastBlockTime = time.Now()
 					cc.lastBlockAlarm = false
 					info := getAlarms(cc.name)
+					cc.resultsMux.Lock()
 					cc.blocksResults = append([]int{int(signState)}, cc.blocksResults[:len(cc.blocksResults)-1]...)
+					cc.historical = append([]bool{false}, cc.historical[:len(cc.historical)-1]...)
+					cc.resultsMux.Unlock()
 					if signState < 3 && cc.valInfo.Bonded {
 						warn := fmt.Sprintf("❌ warning      %s missed block %d on %s", cc.valInfo.Moniker, update.Height, cc.ChainId)
 						info += warn + "\n"
 						cc.lastError = time.Now().UTC().String() + " " + info
 						l(warn)
 					}
+					if round, ok := expectedProposer[update.Height]; ok && signState != StatusProposed && cc.valInfo.Bonded {
+						cc.statProposalMiss += 1
+						cc.proposalMissed = update.Height
+						warn := fmt.Sprintf("❌ warning      %s was the proposer for round %d but did not propose block %d on %s", cc.valInfo.Moniker, round, update.Height, cc.ChainId)
+						info += warn + "\n"
+						cc.lastError = time.Now().UTC().String() + " " + info
+						l(warn)
+					}
+					for h := range expectedProposer {
+						if h <= update.Height {
+							delete(expectedProposer, h)
+						}
+					}
 					switch signState {
 					case Statusmissed:
 						cc.statTotalMiss += 1
@@ -149,13 +350,16 @@ func (cc *ChainConfig) WsRun() {
 						cc.statTotalSigns += 1
 						cc.statConsecutiveMiss = 0
 					}
-					signState = -1
 					healthyNodes := 0
 					for i := range cc.Nodes {
-						if !cc.Nodes[i].down {
+						switch {
+						case cc.Nodes[i].usable():
 							healthyNodes += 1
-						} else if !td.HideLogs { // only show this info if sending logs, the point is not to leak host info
+						case td.HideLogs: // only show this info if sending logs, the point is not to leak host info
+						case cc.Nodes[i].down:
 							info += "\n - " + cc.Nodes[i].lastMsg
+						default:
+							info += "\n - " + cc.Nodes[i].inconsistent
 						}
 					}
 					switch {
@@ -181,13 +385,18 @@ func (cc *ChainConfig) WsRun() {
 							ActiveAlerts: cc.activeAlerts,
 							Height:       update.Height,
 							LastError:    info,
+							Versions:     cc.nodeVersions(),
+							Scores:       cc.nodeScores(),
+							SdkVersion:   cc.sdkVersion,
 							Blocks:       cc.blocksResults,
+							Historical:   cc.historical,
 						}
 					}
 
 					if td.Prom {
 						td.statsChan <- cc.mkUpdate(metricSigned, cc.statTotalSigns, "")
 						td.statsChan <- cc.mkUpdate(metricProposed, cc.statTotalProps, "")
+						td.statsChan <- cc.mkUpdate(metricProposalMissed, cc.statProposalMiss, "")
 						td.statsChan <- cc.mkUpdate(metricMissed, cc.statTotalMiss, "")
 						td.statsChan <- cc.mkUpdate(metricPrevote, cc.statPrevoteMiss, "")
 						td.statsChan <- cc.mkUpdate(metricPrecommit, cc.statPrecommitMiss, "")
@@ -201,63 +410,23 @@ func (cc *ChainConfig) WsRun() {
 		}
 	}()
 
-	voteChan := make(chan *WsReply)
-	go handleVotes(ctx, voteChan, resultChan, strings.ToUpper(hex.EncodeToString(cc.valInfo.Conspub)))
-
-	blockChan := make(chan *WsReply)
+	chans := &eventChans{
+		votes:   make(chan *WsReply),
+		rounds:  make(chan *WsReply),
+		blocks:  make(chan *rawBlock),
+		results: resultChan,
+		address: address,
+	}
+	go handleVotes(ctx, dec, chans.votes, resultChan, address)
+	go handleRounds(ctx, dec, chans.rounds, proposerChan, address)
 	go func() {
-		e := handleBlocks(ctx, blockChan, resultChan, strings.ToUpper(hex.EncodeToString(cc.valInfo.Conspub)))
+		e := handleBlocks(ctx, chans.blocks, resultChan, address)
 		if e != nil {
 			l("🛑", cc.ChainId, e)
 			cancel()
 		}
 	}()
-
-	// now that channel consumers are up, create our subscriptions and route data.
-	go func() {
-		var msg []byte
-		var e error
-		for {
-			_, msg, e = cc.wsclient.ReadMessage()
-			if e != nil {
-				l(e)
-				cancel()
-				return
-			}
-			reply := &WsReply{}
-			e = json.Unmarshal(msg, reply)
-			if e != nil {
-				continue
-			}
-			switch reply.Type() {
-			case `tendermint/event/NewBlock`:
-				blockChan <- reply
-			case `tendermint/event/Vote`:
-				voteChan <- reply
-			default:
-				// fmt.Println("unknown response", reply.Type())
-			}
-		}
-	}()
-
-	for _, subscribe := range []string{QueryNewBlock, QueryVote} {
-		q := fmt.Sprintf(`{"jsonrpc":"2.0","method":"subscribe","id":1,"params":{"query":"%s"}}`, subscribe)
-		err = cc.wsclient.WriteMessage(websocket.TextMessage, []byte(q))
-		if err != nil {
-			l(err)
-			cancel()
-			break
-		}
-	}
-	l(fmt.Sprintf("⚙️ %-12s watching for NewBlock and Vote events via %s", cc.ChainId, cc.client.Remote()))
-	for {
-		select {
-		case <-cc.client.Quit():
-			cancel()
-		case <-ctx.Done():
-			return
-		}
-	}
+	return chans
 }
 
 type stringInt64 string
@@ -268,6 +437,12 @@ func (si stringInt64) val() int64 {
 	return i
 }
 
+// UnmarshalJSON accepts either a quoted or bare number, not every node version quotes 64-bit integers.
+func (si *stringInt64) UnmarshalJSON(b []byte) error {
+	*si = stringInt64(strings.Trim(string(b), `"`))
+	return nil
+}
+
 type signature struct {
 	ValidatorAddress string `json:"validator_address"`
 }
@@ -277,6 +452,7 @@ type rawBlock struct {
 	Block struct {
 		Header struct {
 			Height          stringInt64 `json:"height"`
+			Time            time.Time   `json:"time"`
 			ProposerAddress string      `json:"proposer_address"`
 		} `json:"header"`
 		LastCommit struct {
@@ -300,7 +476,7 @@ func (rb rawBlock) find(val string) bool {
 
 // handleBlocks consumes the channel for new blocks and when it sees one sends a status update. It's also
 // responsible for stalled chain detection and will shutdown the client if there are no blocks for a minute.
-func handleBlocks(ctx context.Context, blocks chan *WsReply, results chan StatusUpdate, address string) error {
+func handleBlocks(ctx context.Context, blocks chan *rawBlock, results chan StatusUpdate, address string) error {
 	live := time.NewTicker(time.Minute)
 	defer live.Stop()
 	lastBlock := time.Now()
@@ -311,18 +487,13 @@ func handleBlocks(ctx context.Context, blocks chan *WsReply, results chan Status
 			if lastBlock.Before(time.Now().Add(-time.Minute)) {
 				return errors.New("websocket idle for 1 minute, exiting")
 			}
-		case block := <-blocks:
+		case b := <-blocks:
 			lastBlock = time.Now()
-			b := &rawBlock{}
-			err := json.Unmarshal(block.Value(), b)
-			if err != nil {
-				l("could not decode block", err)
-				continue
-			}
 			upd := StatusUpdate{
 				Height: b.Block.Header.Height.val(),
 				Status: Statusmissed,
 				Final:  true,
+				Time:   b.Block.Header.Time,
 			}
 			if b.Block.Header.ProposerAddress == address {
 				upd.Status = StatusProposed
@@ -346,12 +517,11 @@ type rawVote struct {
 }
 
 // handleVotes consumes the channel for precommits and prevotes, tracking where in the process a validator is.
-func handleVotes(ctx context.Context, votes chan *WsReply, results chan StatusUpdate, address string) {
+func handleVotes(ctx context.Context, dec *eventDecoder, votes chan *WsReply, results chan StatusUpdate, address string) {
 	for {
 		select {
 		case reply := <-votes:
-			vote := &rawVote{}
-			err := json.Unmarshal(reply.Value(), vote)
+			vote, err := dec.vote(reply.Value())
 			if err != nil {
 				l(err)
 				continue
@@ -377,16 +547,86 @@ func handleVotes(ctx context.Context, votes chan *WsReply, results chan StatusUp
 	}
 }
 
+// rawRound is a trimmed down version of the new round event.
+type rawRound struct {
+	Height   stringInt64 `json:"height"`
+	Round    int32       `json:"round"`
+	Proposer struct {
+		Address string `json:"address"`
+	} `json:"proposer"`
+}
+
+// roundProposer is sent when our validator is the expected proposer for a round.
+type roundProposer struct {
+	height int64
+	round  int32
+}
+
+// handleRounds consumes the channel for new rounds, and reports when the validator is the expected proposer. If the
+// block at that height is not proposed by the validator, the proposal was missed.
+func handleRounds(ctx context.Context, dec *eventDecoder, rounds chan *WsReply, proposers chan *roundProposer, address string) {
+	for {
+		select {
+		case reply := <-rounds:
+			round, err := dec.round(reply.Value())
+			if err != nil {
+				l(err)
+				continue
+			}
+			if round.Proposer.Address == address {
+				proposers <- &roundProposer{height: round.Height.val(), round: round.Round}
+			}
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
 // TmConn is the websocket client. This is probably not necessary since I expected more complexity.
 type TmConn struct {
 	*websocket.Conn
+	remote string // the RPC url the websocket was opened for
 }
 
-// NewClient returns a websocket client.
-// FIXME: need to handle UDS and insecure TLS
-func NewClient(u string, allowInsecure bool) (*TmConn, error) {
-	// dialUnix is used to determine if the connection is to a UDS and requires a custom dialer.
-	var dialUnix bool
+// redundantNodes returns up to n healthy nodes, best score first, other than the one used by the rpc client, for additional websocket
+// subscriptions. Events are decoded the same way for every websocket, so nodes known to be running a different
+// consensus version are skipped.
+func (cc *ChainConfig) redundantNodes(version string, n int) []string {
+	urls := make([]string, 0)
+	for _, node := range cc.rankedNodes() {
+		if len(urls) >= n {
+			break
+		}
+		if !node.usable() || node.Url == cc.client.Remote() {
+			continue
+		}
+		if v, _ := consensusVersion(node.version); node.version != "" && v != version {
+			l(fmt.Sprintf("⚠️ %-12s not subscribing to %s, it is running %s", cc.ChainId, node.Url, node.version))
+			continue
+		}
+		urls = append(urls, node.Url)
+	}
+	return urls
+}
+
+// NewClient returns a websocket client. unix:// URLs connect to the socket's path. base has the TLS and proxy
+// settings, nil uses the defaults. header is sent with the handshake, for authentication.
+func NewClient(u string, allowInsecure bool, base *websocket.Dialer, header http.Header) (*TmConn, error) {
+	remote := u
+	if base == nil {
+		base = websocket.DefaultDialer
+	}
+	dialer := *base
+
+	// a UDS needs a custom dialer, the websocket's URL only provides the path.
+	if strings.HasPrefix(u, "unix://") {
+		socket := strings.TrimPrefix(u, "unix://")
+		dialer.NetDialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		}
+		u = "ws://localhost"
+	}
 
 	// normalize the path, some public rpcs prefix with /rpc or similar.
 	u = strings.TrimRight(u, "/")
@@ -403,35 +643,20 @@ func NewClient(u string, allowInsecure bool) (*TmConn, error) {
 	switch endpoint.Scheme {
 	case "http", "tcp", "ws":
 		endpoint.Scheme = "ws"
-	case "unix":
-		dialUnix = true
-		endpoint.Scheme = "ws"
 	case "https", "wss":
 		endpoint.Scheme = "wss"
 	default:
 		return nil, fmt.Errorf("protocol %s is unknown, valid choices are http, https, tcp, unix, ws, and wss", endpoint.Scheme)
 	}
 
-	// allowInsecure is primarily intended for self-signed certs, but it doesn't make sense to allow yes to for non-tls
+	// allowInsecure is needed for connections that don't use TLS
 	if endpoint.Scheme == "ws" && !allowInsecure {
 		return nil, errors.New("allowInsecure must be true if protocol is not using TLS")
 	}
 
-	conn := &websocket.Conn{}
-
-	switch {
-
-	// TODO: add custom UDS dialer
-	case dialUnix:
-
-	// TODO: add custom TLS dialer to allow self-signed certs.
-	// case allowInsecure && endpoint.Scheme == "wss":
-
-	default:
-		conn, _, err = websocket.DefaultDialer.Dial(endpoint.String(), nil)
-		if err != nil {
-			return nil, fmt.Errorf("could not dial ws client to %s: %s", endpoint.String(), err.Error())
-		}
+	conn, _, err := dialer.Dial(endpoint.String(), header)
+	if err != nil {
+		return nil, fmt.Errorf("could not dial ws client to %s: %s", remote, err.Error())
 	}
-	return &TmConn{Conn: conn}, nil
+	return &TmConn{Conn: conn, remote: remote}, nil
 }